@@ -0,0 +1,67 @@
+// Package fail annotates errors with parameters, in the style of github.com/srvc/fail.
+// Error implements Unwrap, so errors.Is and errors.As see through any number of Wrap.
+package fail
+
+import "errors"
+
+// H is parameters of an error
+type H map[string]interface{}
+
+// Error is an error annotated with parameters
+type Error struct {
+	Err    error
+	Params H
+}
+
+// Error returns the message of the original error. Parameters are kept for logging, not printed.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Annotator adds details to an error
+type Annotator func(err *Error)
+
+// New returns an error that formats as text
+func New(text string) error {
+	return &Error{Err: errors.New(text)}
+}
+
+// Wrap annotates err. It returns nil when err is nil.
+// Wrapping an Error again adds to its parameters instead of nesting it.
+func Wrap(err error, annotators ...Annotator) error {
+	if err == nil {
+		return nil
+	}
+
+	wrapped, ok := err.(*Error)
+	if ok {
+		wrapped = &Error{Err: wrapped.Err, Params: H{}.merge(wrapped.Params)}
+	} else {
+		wrapped = &Error{Err: err}
+	}
+	for _, annotate := range annotators {
+		annotate(wrapped)
+	}
+	return wrapped
+}
+
+// WithParam adds key and value to parameters of the error
+func WithParam(key string, value interface{}) Annotator {
+	return func(err *Error) {
+		if err.Params == nil {
+			err.Params = H{}
+		}
+		err.Params[key] = value
+	}
+}
+
+func (h H) merge(other H) H {
+	for key, value := range other {
+		h[key] = value
+	}
+	return h
+}
@@ -0,0 +1,24 @@
+package fail_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+func TestWrap(t *testing.T) {
+	err := fail.Wrap(fail.Wrap(io.EOF, fail.WithParam("key", "a")), fail.WithParam("order", 2))
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("want io.EOF but got %v", err)
+	}
+	if diff := cmp.Diff(err.(*fail.Error).Params, fail.H{"key": "a", "order": 2}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	if fail.Wrap(nil) != nil {
+		t.Error("want nil")
+	}
+}
@@ -0,0 +1,138 @@
+package redblocks
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+type EventType int
+
+const (
+	// KeySaved is published when keys are written
+	KeySaved EventType = iota
+	// KeyDeleted is published when keys are deleted
+	KeyDeleted
+	// BusReconnected is delivered after a lost subscription is restored. Events in between are lost, so treat all keys as changed.
+	BusReconnected
+)
+
+func (t EventType) String() string {
+	switch t {
+	case KeySaved:
+		return "KeySaved"
+	case KeyDeleted:
+		return "KeyDeleted"
+	case BusReconnected:
+		return "BusReconnected"
+	default:
+		return ""
+	}
+}
+
+// Event tells that keys are changed, possibly by another instance.
+type Event struct {
+	Type EventType `json:"type"`
+	Keys []string  `json:"keys,omitempty"`
+}
+
+// Bus delivers events to all instances, including the one which published them.
+type Bus interface {
+	Publish(ctx context.Context, event Event) error
+	// Subscribe calls handler with events until ctx is done. A lost subscription is restored, then BusReconnected is delivered.
+	Subscribe(ctx context.Context, handler func(ctx context.Context, event Event)) error
+}
+
+// Listener is notified of events by Listen, e.g. a local cache or Refresher.
+type Listener interface {
+	Notify(ctx context.Context, event Event)
+}
+
+// ListenerFunc is a function implementing Listener.
+type ListenerFunc func(ctx context.Context, event Event)
+
+func (f ListenerFunc) Notify(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+// Listen subscribes bus and notifies listeners of each event until ctx is done.
+func Listen(ctx context.Context, bus Bus, listeners ...Listener) error {
+	return fail.Wrap(bus.Subscribe(ctx, func(ctx context.Context, event Event) {
+		for _, l := range listeners {
+			l.Notify(ctx, event)
+		}
+	}))
+}
+
+func encodeEvent(event Event) ([]byte, error) {
+	b, err := json.Marshal(event)
+	return b, fail.Wrap(err)
+}
+
+func decodeEvent(b []byte) (Event, error) {
+	var event Event
+	err := json.Unmarshal(b, &event)
+	return event, fail.Wrap(err)
+}
+
+// MemoryBus is Bus in a process. It is a stand-in of the Redis backed buses in tests.
+type MemoryBus struct {
+	mu       sync.Mutex
+	handlers map[int]func(ctx context.Context, event Event)
+	next     int
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{handlers: map[int]func(ctx context.Context, event Event){}}
+}
+
+// Publish calls handlers of all subscriptions before returning.
+func (b *MemoryBus) Publish(ctx context.Context, event Event) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(ctx, err)
+	}
+	b.deliver(ctx, event)
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, handler func(ctx context.Context, event Event)) error {
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.handlers, id)
+	b.mu.Unlock()
+	return nil
+}
+
+// Subscribers returns the number of active subscriptions, so tests can wait for Subscribe.
+func (b *MemoryBus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.handlers)
+}
+
+// Reconnect delivers BusReconnected to all subscriptions, as if their connections were restored.
+func (b *MemoryBus) Reconnect(ctx context.Context) {
+	b.deliver(ctx, Event{Type: BusReconnected})
+}
+
+func (b *MemoryBus) deliver(ctx context.Context, event Event) {
+	b.mu.Lock()
+	handlers := make([]func(ctx context.Context, event Event), 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+}
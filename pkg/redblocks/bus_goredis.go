@@ -0,0 +1,76 @@
+package redblocks
+
+import (
+	"context"
+	"time"
+
+	go_redis "github.com/go-redis/redis/v8"
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+type goredisBusImp struct {
+	redisClientFunc RedisClientFunc
+	opt             BusOption
+}
+
+// NewGoredisBus returns Bus over Redis pub/sub.
+func NewGoredisBus(redisClientFunc RedisClientFunc, opts ...BusOption) Bus {
+	opt, _ := BusOptionsToBusOption(opts)
+	return goredisBusImp{redisClientFunc: redisClientFunc, opt: opt}
+}
+
+func (b goredisBusImp) Publish(ctx context.Context, event Event) error {
+	payload, err := encodeEvent(event)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+
+	redisClient := b.redisClientFunc(ctx)
+	return fail.Wrap(storeErr(ctx, redisClient.Publish(ctx, b.opt.Channel, payload).Err()))
+}
+
+// Subscribe relies on go-redis to restore the subscription. It is subscribed again on the next Receive after an error.
+func (b goredisBusImp) Subscribe(ctx context.Context, handler func(ctx context.Context, event Event)) error {
+	redisClient := b.redisClientFunc(ctx)
+	pubsub := redisClient.Subscribe(ctx, b.opt.Channel)
+	defer pubsub.Close()
+
+	subscribed := false
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if b.opt.OnError != nil {
+				b.opt.OnError(storeErr(ctx, err))
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(b.opt.RetryInterval):
+			}
+			continue
+		}
+
+		switch v := msg.(type) {
+		case *go_redis.Subscription:
+			if v.Kind != "subscribe" {
+				continue
+			}
+			if subscribed {
+				handler(ctx, Event{Type: BusReconnected})
+			}
+			subscribed = true
+		case *go_redis.Message:
+			event, err := decodeEvent([]byte(v.Payload))
+			if err != nil {
+				if b.opt.OnError != nil {
+					b.opt.OnError(err)
+				}
+				continue
+			}
+			handler(ctx, event)
+		}
+	}
+}
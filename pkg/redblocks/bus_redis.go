@@ -0,0 +1,127 @@
+package redblocks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+type redisBusImp struct {
+	pool *redis.Pool
+	opt  BusOption
+}
+
+// NewRedisBus returns Bus over Redis pub/sub.
+func NewRedisBus(pool *redis.Pool, opts ...BusOption) Bus {
+	opt, _ := BusOptionsToBusOption(opts)
+	return redisBusImp{pool: pool, opt: opt}
+}
+
+func (b redisBusImp) Publish(ctx context.Context, event Event) error {
+	payload, err := encodeEvent(event)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+
+	conn, err := b.pool.GetContext(ctx)
+	if err != nil {
+		return fail.Wrap(storeErr(ctx, err))
+	}
+	defer conn.Close()
+
+	_, err = do(ctx, conn, "PUBLISH", b.opt.Channel, payload)
+	return fail.Wrap(err)
+}
+
+func (b redisBusImp) Subscribe(ctx context.Context, handler func(ctx context.Context, event Event)) error {
+	for subscribed := false; ; {
+		err := b.subscribe(ctx, handler, &subscribed)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if b.opt.OnError != nil {
+			b.opt.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(b.opt.RetryInterval):
+		}
+	}
+}
+
+// subscribe receives events until the connection is lost. subscribed is set after the first subscription.
+// The read timeout of the pool does not apply, so an idle subscription is kept while it answers PING.
+func (b redisBusImp) subscribe(ctx context.Context, handler func(ctx context.Context, event Event), subscribed *bool) error {
+	conn, err := b.pool.GetContext(ctx)
+	if err != nil {
+		return fail.Wrap(storeErr(ctx, err))
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+
+	if err := psc.Subscribe(b.opt.Channel); err != nil {
+		return fail.Wrap(storeErr(ctx, err))
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b.ping(ctx, psc, done)
+	}()
+	defer wg.Wait()
+	defer close(done)
+
+	for {
+		switch v := psc.ReceiveWithTimeout(b.opt.PingInterval * 2).(type) {
+		case redis.Subscription:
+			// Unsubscribed by ping when ctx is done
+			if v.Count == 0 {
+				return nil
+			}
+			if v.Kind != "subscribe" {
+				continue
+			}
+			if *subscribed {
+				handler(ctx, Event{Type: BusReconnected})
+			}
+			*subscribed = true
+		case redis.Message:
+			event, err := decodeEvent(v.Data)
+			if err != nil {
+				if b.opt.OnError != nil {
+					b.opt.OnError(err)
+				}
+				continue
+			}
+			handler(ctx, event)
+		case error:
+			return fail.Wrap(storeErr(ctx, v))
+		}
+	}
+}
+
+// ping sends PING every PingInterval until done, and unsubscribes when ctx is done so that Receive returns.
+func (b redisBusImp) ping(ctx context.Context, psc redis.PubSubConn, done <-chan struct{}) {
+	ticker := time.NewTicker(b.opt.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			psc.Unsubscribe()
+			return
+		case <-ticker.C:
+			if err := psc.Ping(""); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package redblocks_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func TestMemoryBus(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	shared := redblocks.NewMemoryStore(redblocks.WithClock(clock.Now))
+	bus := redblocks.NewMemoryBus()
+
+	// Pod A writes through the publishing store, pod B refreshes the same set
+	tokyoA := redblocks.Compose(NewScoredSet("TestMemoryBus", []redblocks.IDWithScore{{ID: "1", Score: 1}}), redblocks.NewPublishingStore(shared, bus, nil))
+	tokyoB := redblocks.Compose(NewScoredSet("TestMemoryBus", []redblocks.IDWithScore{{ID: "1", Score: 1}}), shared)
+	refresher := redblocks.NewRefresher(shared, redblocks.WithRefreshClock(clock.Now), redblocks.WithRefreshJitter(-1))
+	refresher.Register(tokyoB)
+
+	var mu sync.Mutex
+	var events []redblocks.Event
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- redblocks.Listen(ctx, bus, refresher, redblocks.ListenerFunc(func(ctx context.Context, event redblocks.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		}))
+	}()
+	for bus.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := tokyoA.Warmup(ctx); err != nil {
+		t.Error(err)
+	}
+	refresher.RefreshDue(ctx)
+	if status, _ := refresher.Status(tokyoB.Key()); status.NextRefresh.IsZero() {
+		t.Errorf("expected to be scheduled")
+	}
+
+	if err := tokyoA.Invalidate(ctx); err != nil {
+		t.Error(err)
+	}
+	if status, _ := refresher.Status(tokyoB.Key()); !status.NextRefresh.IsZero() {
+		t.Errorf("expected to be due, got %v", status.NextRefresh)
+	}
+	refresher.RefreshDue(ctx)
+	exists, err := shared.Exists(ctx, tokyoB.Key())
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(exists, true); diff != "" {
+		t.Errorf(diff)
+	}
+
+	bus.Reconnect(ctx)
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+
+	expected := []redblocks.Event{
+		{Type: redblocks.KeySaved, Keys: []string{tokyoA.Key()}},
+		{Type: redblocks.KeyDeleted, Keys: []string{tokyoA.Key()}},
+		{Type: redblocks.BusReconnected},
+	}
+	if diff := cmp.Diff(events, expected); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestRedisBusUnavailable(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	bus := redblocks.NewRedisBus(&redis.Pool{
+		Dial: func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:1") },
+	}, redblocks.WithBusRetryInterval(time.Millisecond), redblocks.WithBusOnError(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if err := bus.Subscribe(ctx, func(ctx context.Context, event redblocks.Event) {}); err != nil {
+		t.Error(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Retried until ctx is done
+	if len(errs) < 2 {
+		t.Errorf("expected retries, got %v", errs)
+	}
+	if !errors.Is(errs[0], redblocks.ErrStoreUnavailable) {
+		t.Errorf("want ErrStoreUnavailable but got %v", errs[0])
+	}
+}
+
+func TestRedisBus(t *testing.T) {
+	bus := redblocks.NewRedisBus(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:6379") },
+	}, redblocks.WithBusChannel("TestRedisBus"))
+	testBus(t, bus, 0)
+}
+
+func TestRedisBusIdle(t *testing.T) {
+	bus := redblocks.NewRedisBus(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", "localhost:6379", redis.DialReadTimeout(time.Millisecond*100))
+		},
+	}, redblocks.WithBusChannel("TestRedisBusIdle"), redblocks.WithBusPingInterval(time.Millisecond*20))
+	// Idle for longer than the read timeout of the pool, which must not lose the subscription
+	testBus(t, bus, time.Millisecond*300)
+}
+
+func TestGoredisBus(t *testing.T) {
+	bus := redblocks.NewGoredisBus(redisdb.WithContext, redblocks.WithBusChannel("TestGoredisBus"))
+	testBus(t, bus, 0)
+}
+
+// testBus publishes an event after idle and expects it to be the first event received
+func testBus(t *testing.T, bus redblocks.Bus, idle time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	received := make(chan redblocks.Event, 1)
+	go bus.Subscribe(ctx, func(ctx context.Context, event redblocks.Event) {
+		// Events published while waiting for the first one are dropped
+		select {
+		case received <- event:
+		default:
+		}
+	})
+	time.Sleep(idle)
+
+	event := redblocks.Event{Type: redblocks.KeyDeleted, Keys: []string{t.Name()}}
+	// Published until the subscription is ready
+	for {
+		if err := bus.Publish(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got := <-received:
+			if diff := cmp.Diff(got, event); diff != "" {
+				t.Errorf(diff)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("not received")
+		case <-time.After(time.Millisecond * 50):
+		}
+	}
+}
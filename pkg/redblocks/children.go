@@ -0,0 +1,66 @@
+package redblocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+// Parent is implemented by sets which are derived from other sets, such as union, intersection and subtraction.
+type Parent interface {
+	Children() []ComposedSet
+}
+
+// Children returns sets which set is derived from. It returns nil for leaf sets.
+func Children(set interface{}) []ComposedSet {
+	if p, ok := set.(Parent); ok {
+		return p.Children()
+	}
+	return nil
+}
+
+// unionOnly rejects SkipFailedChildren for operators other than union
+func unionOnly(opt OperatorOption) error {
+	if opt.FailurePolicy != FailOnChildError {
+		return fail.Wrap(ErrUnsupportedFailurePolicy, fail.WithParam("policy", opt.FailurePolicy))
+	}
+	return nil
+}
+
+// warmupChildren warms up sets concurrently. Failures are handled by opt.FailurePolicy.
+func warmupChildren(ctx context.Context, sets []ComposedSet, opt OperatorOption) error {
+	errs := make([]error, len(sets), len(sets))
+	sem := make(chan struct{}, opt.Concurrency)
+	var wg sync.WaitGroup
+	for i, set := range sets {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, set ComposedSet) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = set.Warmup(ctx)
+		}(i, set)
+	}
+	wg.Wait()
+
+	failed := map[string]error{}
+	for i, err := range errs {
+		if err != nil {
+			failed[sets[i].Key()] = err
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	if opt.FailurePolicy == SkipFailedChildren && len(failed) < len(sets) {
+		if opt.OnChildError != nil {
+			for key, err := range failed {
+				opt.OnChildError(key, err)
+			}
+		}
+		return nil
+	}
+	return &WarmupError{Errs: failed}
+}
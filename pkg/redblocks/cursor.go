@@ -0,0 +1,74 @@
+package redblocks
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+// Cursor is an opaque position in a set which is returned by IDsWithCursor.
+// It remembers the last (score, ID) pair, so paging is stable even if the set is updated between requests.
+// It also remembers how many members with the last score are returned, so the next page starts at them instead of skipping them.
+// Empty cursor means the beginning of the set. Empty cursor is also returned when there are no more members.
+type Cursor string
+
+func NewCursor(last IDWithScore) Cursor {
+	return newCursor(last, 0)
+}
+
+// newCursor remembers ties, the number of returned members with the score of last. Zero means unknown.
+func newCursor(last IDWithScore, ties int64) Cursor {
+	raw := strconv.FormatFloat(last.Score, 'g', -1, 64) + ":" + strconv.FormatInt(ties, 10) + ":" + string(last.ID)
+	return Cursor(base64.RawURLEncoding.EncodeToString([]byte(raw)))
+}
+
+func (c Cursor) Decode() (IDWithScore, error) {
+	last, _, err := c.decode()
+	return last, fail.Wrap(err)
+}
+
+func (c Cursor) decode() (IDWithScore, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(string(c))
+	if err != nil {
+		return IDWithScore{}, 0, fail.Wrap(err, fail.WithParam("cursor", c))
+	}
+
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) != 3 {
+		return IDWithScore{}, 0, fail.Wrap(fail.New("Invalid cursor"), fail.WithParam("cursor", c))
+	}
+	score, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return IDWithScore{}, 0, fail.Wrap(err, fail.WithParam("cursor", c))
+	}
+	ties, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || ties < 0 {
+		return IDWithScore{}, 0, fail.Wrap(fail.New("Invalid cursor"), fail.WithParam("cursor", c))
+	}
+
+	return IDWithScore{ID: ID(parts[2]), Score: score}, ties, nil
+}
+
+// countTies returns the number of members with the score of the last member of page, in page and pages before it.
+// before is the number of members with the score of the first member of page, which are returned by pages before it.
+func countTies(page []IDWithScore, before int64) int64 {
+	tail := page[len(page)-1]
+	var n int64
+	for i := len(page) - 1; i >= 0 && page[i].Score == tail.Score; i-- {
+		n++
+	}
+	if n == int64(len(page)) {
+		n += before
+	}
+	return n
+}
+
+// after reports whether m comes after last in order. Members with the same score are ordered by ID, same as Redis.
+func after(m IDWithScore, last IDWithScore, order Order) bool {
+	if order == Desc {
+		return m.Score < last.Score || (m.Score == last.Score && m.ID < last.ID)
+	}
+	return m.Score > last.Score || (m.Score == last.Score && m.ID > last.ID)
+}
@@ -0,0 +1,177 @@
+package redblocks_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func TestIDsWithCursor(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	set := redblocks.Compose(NewScoredSet("TestIDsWithCursor", []redblocks.IDWithScore{
+		{ID: "a", Score: 1},
+		{ID: "b", Score: 1},
+		{ID: "c", Score: 1},
+		{ID: "d", Score: 2},
+		{ID: "e", Score: 3},
+	}), store)
+	ctx := context.Background()
+
+	ids, cursor, err := set.IDsWithCursor(ctx, "", 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"a", "b"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	// Refreshed between requests
+	err = store.Save(ctx, set.Key(), []redblocks.IDWithScore{
+		{ID: "0", Score: 0},
+		{ID: "aa", Score: 1},
+		{ID: "b", Score: 1},
+		{ID: "bb", Score: 1},
+		{ID: "d", Score: 2},
+		{ID: "e", Score: 3},
+	}, time.Second*100)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ids, cursor, err = set.IDsWithCursor(ctx, cursor, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"bb", "d"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	ids, cursor, err = set.IDsWithCursor(ctx, cursor, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"e"}); diff != "" {
+		t.Errorf(diff)
+	}
+	if diff := cmp.Diff(cursor, redblocks.Cursor("")); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestIDsWithScoreWithCursorDesc(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	set := redblocks.Compose(NewScoredSet("TestIDsWithScoreWithCursorDesc", []redblocks.IDWithScore{
+		{ID: "a", Score: 1},
+		{ID: "b", Score: 1},
+		{ID: "c", Score: 1},
+		{ID: "d", Score: 2},
+	}), store)
+	ctx := context.Background()
+
+	result, cursor, err := set.IDsWithScoreWithCursor(ctx, "", 2, redblocks.WithOrder(redblocks.Desc))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "d", Score: 2}, {ID: "c", Score: 1}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	result, _, err = set.IDsWithScoreWithCursor(ctx, cursor, 2, redblocks.WithOrder(redblocks.Desc))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "b", Score: 1}, {ID: "a", Score: 1}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	_, _, err = set.IDsWithScoreWithCursor(ctx, "invalid cursor", 2)
+	if err == nil {
+		t.Errorf("Expected not nil")
+	}
+}
+
+func TestIDsWithCursorTiedScores(t *testing.T) {
+	o := &recordingObserver{}
+	store := redblocks.NewObservedStore(redblocks.NewMemoryStore(), o)
+	idsWithScore := make([]redblocks.IDWithScore, 2000)
+	for i := range idsWithScore {
+		idsWithScore[i] = redblocks.IDWithScore{ID: redblocks.ID(fmt.Sprintf("%04d", i)), Score: 1}
+	}
+	set := redblocks.Compose(NewScoredSet("TestIDsWithCursorTiedScores", idsWithScore), store)
+	ctx := context.Background()
+
+	for _, order := range []redblocks.Order{redblocks.Asc, redblocks.Desc} {
+		o.calls = nil
+		var result []redblocks.ID
+		var cursor redblocks.Cursor
+		for {
+			ids, next, err := set.IDsWithCursor(ctx, cursor, 20, redblocks.WithOrder(order))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result = append(result, ids...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		expected := make([]redblocks.ID, len(idsWithScore))
+		for i, idWithScore := range idsWithScore {
+			if order == redblocks.Desc {
+				i = len(idsWithScore) - 1 - i
+			}
+			expected[i] = idWithScore.ID
+		}
+		if diff := cmp.Diff(result, expected); diff != "" {
+			t.Errorf(diff)
+		}
+		// One call per page and one more for the empty page at the end
+		if diff := cmp.Diff(o.calls["GetIDsWithScoreByScore"], 2000/20+1); diff != "" {
+			t.Errorf(diff)
+		}
+	}
+}
+
+func TestIDsWithCursorTiedScoresChanged(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	set := redblocks.Compose(NewScoredSet("TestIDsWithCursorTiedScoresChanged", []redblocks.IDWithScore{
+		{ID: "b", Score: 1},
+		{ID: "c", Score: 1},
+		{ID: "d", Score: 1},
+		{ID: "e", Score: 1},
+	}), store)
+	ctx := context.Background()
+
+	ids, cursor, err := set.IDsWithCursor(ctx, "", 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"b", "c"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	// A member is added before the cursor, so the cursor is not at the remembered position
+	err = store.Save(ctx, set.Key(), []redblocks.IDWithScore{
+		{ID: "a", Score: 1},
+		{ID: "b", Score: 1},
+		{ID: "c", Score: 1},
+		{ID: "d", Score: 1},
+		{ID: "e", Score: 1},
+	}, time.Second*100)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ids, _, err = set.IDsWithCursor(ctx, cursor, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"d", "e"}); diff != "" {
+		t.Errorf(diff)
+	}
+}
@@ -0,0 +1,96 @@
+package redblocks
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+// Delta is a change of a set since a watermark. Store applies Removed, Added and Incremented in this order.
+type Delta struct {
+	Added       []IDWithScore // Added members or members whose score is replaced, like ZADD
+	Removed     []ID
+	Incremented []IDWithScore // Score is added to the current score, like ZINCRBY
+	Watermark   string        // Position of the source after this delta. It is passed to the next GetDelta.
+}
+
+// DeltaSet is a Set which can return changes since a watermark. Update applies them instead of rebuilding the set by Get.
+// GetDelta is called with an empty watermark when the set is rebuilt, then Added must have all members.
+// Use ComposeWithLock when the set is updated by many processes, since a rebuild is not atomic.
+type DeltaSet interface {
+	Set
+	GetDelta(ctx context.Context, watermark string) (Delta, error)
+}
+
+// WatermarkKey is the key where Redis backed stores keep the watermark of key.
+func WatermarkKey(key string) string {
+	return key + ":watermark"
+}
+
+// validateWatermarkExpire returns an error when expire is shorter than a millisecond, which Redis cannot keep a watermark for.
+func validateWatermarkExpire(expire time.Duration) error {
+	if expire < time.Millisecond {
+		return fail.Wrap(fail.New("expire must be at least a millisecond"), fail.WithParam("expire", expire))
+	}
+	return nil
+}
+
+// validateDelta checks delta before a store writes anything, so a delta is applied entirely or not at all.
+func validateDelta(delta Delta, expire time.Duration) error {
+	if err := validateWatermarkExpire(expire); err != nil {
+		return fail.Wrap(err)
+	}
+	for _, idsWithScore := range [][]IDWithScore{delta.Added, delta.Incremented} {
+		for _, v := range idsWithScore {
+			if math.IsNaN(v.Score) {
+				return fail.Wrap(fail.New("score must not be NaN"), fail.WithParam("id", v.ID))
+			}
+		}
+	}
+	return nil
+}
+
+func (c withUpdateImp) updateDelta(ctx context.Context, set DeltaSet) error {
+	key := c.Key()
+	watermark, ok, err := c.store.Watermark(ctx, key)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	if ok {
+		exists, err := c.store.Exists(ctx, key)
+		if err != nil {
+			return fail.Wrap(err)
+		}
+		if exists {
+			delta, err := set.GetDelta(ctx, watermark)
+			if err != nil {
+				return fail.Wrap(err)
+			}
+			applied, err := c.store.ApplyDelta(ctx, key, watermark, delta, c.CacheTime())
+			if err != nil || applied {
+				return fail.Wrap(err)
+			}
+			// Not applied when someone else applied it first, and the set is up to date anyway.
+			// It is not applied either when key expired or was invalidated since Exists, then the set is rebuilt.
+			exists, err = c.store.Exists(ctx, key)
+			if err != nil || exists {
+				return fail.Wrap(err)
+			}
+		}
+	}
+
+	// The watermark is removed first, so increments are never applied twice if the rebuild fails halfway
+	if err := c.store.SetWatermark(ctx, key, "", 0); err != nil {
+		return fail.Wrap(err)
+	}
+	delta, err := set.GetDelta(ctx, "")
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	if err := c.store.Save(ctx, key, delta.Added, c.CacheTime()); err != nil {
+		return fail.Wrap(err)
+	}
+	return fail.Wrap(c.store.SetWatermark(ctx, key, delta.Watermark, c.CacheTime()))
+}
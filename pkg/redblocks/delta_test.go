@@ -0,0 +1,279 @@
+package redblocks_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+type deltaSetImp struct {
+	scoredSetImp
+	deltas     map[string]redblocks.Delta
+	watermarks *[]string
+}
+
+func (s deltaSetImp) GetDelta(ctx context.Context, watermark string) (redblocks.Delta, error) {
+	*s.watermarks = append(*s.watermarks, watermark)
+	return s.deltas[watermark], nil
+}
+
+func TestDeltaSet(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	ctx := context.Background()
+	var watermarks []string
+	set := redblocks.Compose(deltaSetImp{
+		scoredSetImp: scoredSetImp{name: "TestDeltaSet"},
+		deltas: map[string]redblocks.Delta{
+			"": {Added: []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}, Watermark: "v1"},
+			"v1": {
+				Added:       []redblocks.IDWithScore{{ID: "3", Score: 3}},
+				Removed:     []redblocks.ID{"1"},
+				Incremented: []redblocks.IDWithScore{{ID: "2", Score: 5}},
+				Watermark:   "v2",
+			},
+		},
+		watermarks: &watermarks,
+	}, store)
+
+	for _, expected := range [][]redblocks.IDWithScore{
+		{{ID: "1", Score: 1}, {ID: "2", Score: 2}},
+		{{ID: "3", Score: 3}, {ID: "2", Score: 7}},
+	} {
+		if err := set.Update(ctx); err != nil {
+			t.Error(err)
+		}
+		idsWithScore, err := set.IDsWithScore(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff(idsWithScore, expected); diff != "" {
+			t.Errorf(diff)
+		}
+	}
+	if diff := cmp.Diff(watermarks, []string{"", "v1"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	// Someone else applied v1 already
+	applied, err := store.ApplyDelta(ctx, set.Key(), "v1", redblocks.Delta{Incremented: []redblocks.IDWithScore{{ID: "2", Score: 5}}, Watermark: "v2"}, time.Second*100)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(applied, false); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestStoreApplyDelta(t *testing.T) {
+	stores := map[string]redblocks.Store{
+		"memory":  redblocks.NewMemoryStore(),
+		"redis":   redblocks.NewRedisStore(newPool()),
+		"goredis": redblocks.NewGoredisStore(redisdb.WithContext),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testStoreApplyDelta(t, store, "TestStoreApplyDelta:"+name)
+		})
+	}
+}
+
+func testStoreApplyDelta(t *testing.T, store redblocks.Store, key string) {
+	ctx := context.Background()
+	if err := store.Save(ctx, key, []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}}, time.Second*100); err != nil {
+		t.Error(err)
+	}
+	if err := store.SetWatermark(ctx, key, "v1", time.Second*100); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		name      string
+		from      string
+		delta     redblocks.Delta
+		expire    time.Duration
+		applied   bool
+		err       bool
+		expected  []redblocks.IDWithScore
+		watermark string
+	}{
+		{
+			name:      "stale watermark",
+			from:      "v0",
+			delta:     redblocks.Delta{Removed: []redblocks.ID{"a"}, Watermark: "v2"},
+			expire:    time.Second * 100,
+			expected:  []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}},
+			watermark: "v1",
+		},
+		{
+			name:      "expire shorter than a millisecond",
+			from:      "v1",
+			delta:     redblocks.Delta{Removed: []redblocks.ID{"a"}, Watermark: "v2"},
+			expire:    time.Microsecond,
+			err:       true,
+			expected:  []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}},
+			watermark: "v1",
+		},
+		{
+			name:      "NaN score",
+			from:      "v1",
+			delta:     redblocks.Delta{Removed: []redblocks.ID{"a"}, Incremented: []redblocks.IDWithScore{{ID: "b", Score: math.NaN()}}, Watermark: "v2"},
+			expire:    time.Second * 100,
+			err:       true,
+			expected:  []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}},
+			watermark: "v1",
+		},
+		{
+			name: "remove, add and increment",
+			from: "v1",
+			delta: redblocks.Delta{
+				Added:       []redblocks.IDWithScore{{ID: "c", Score: 3}},
+				Removed:     []redblocks.ID{"a"},
+				Incremented: []redblocks.IDWithScore{{ID: "b", Score: 5}},
+				Watermark:   "v2",
+			},
+			expire:    time.Second * 100,
+			applied:   true,
+			expected:  []redblocks.IDWithScore{{ID: "c", Score: 3}, {ID: "b", Score: 7}},
+			watermark: "v2",
+		},
+		{
+			name:     "empty watermark",
+			from:     "v2",
+			delta:    redblocks.Delta{Added: []redblocks.IDWithScore{{ID: "d", Score: 4}}},
+			expire:   time.Second * 100,
+			applied:  true,
+			expected: []redblocks.IDWithScore{{ID: "c", Score: 3}, {ID: "d", Score: 4}, {ID: "b", Score: 7}},
+		},
+		{
+			name:      "remove all members",
+			delta:     redblocks.Delta{Removed: []redblocks.ID{"b", "c", "d"}, Watermark: "v3"},
+			expire:    time.Second * 100,
+			applied:   true,
+			expected:  []redblocks.IDWithScore{},
+			watermark: "v3",
+		},
+		{
+			name:      "missing key",
+			from:      "v3",
+			delta:     redblocks.Delta{Added: []redblocks.IDWithScore{{ID: "e", Score: 5}}, Watermark: "v4"},
+			expire:    time.Second * 100,
+			expected:  []redblocks.IDWithScore{},
+			watermark: "v3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied, err := store.ApplyDelta(ctx, key, tt.from, tt.delta, tt.expire)
+			if diff := cmp.Diff([]interface{}{applied, err != nil}, []interface{}{tt.applied, tt.err}); diff != "" {
+				t.Errorf(diff)
+			}
+
+			idsWithScore, err := store.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(idsWithScore, tt.expected, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf(diff)
+			}
+
+			watermark, ok, err := store.Watermark(ctx, key)
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff([]interface{}{watermark, ok}, []interface{}{tt.watermark, tt.watermark != ""}); diff != "" {
+				t.Errorf(diff)
+			}
+		})
+	}
+
+	// The watermark does not depend on the set, same as WatermarkKey
+	if err := store.Delete(ctx, key); err != nil {
+		t.Error(err)
+	}
+	if err := store.SetWatermark(ctx, key, "v5", time.Second*100); err != nil {
+		t.Error(err)
+	}
+	watermark, ok, err := store.Watermark(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff([]interface{}{watermark, ok}, []interface{}{"v5", true}); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+// vanishingDeltaSetImp deletes its key in GetDelta, as if it expired or was invalidated between Exists and ApplyDelta
+type vanishingDeltaSetImp struct {
+	deltaSetImp
+	vanish func(ctx context.Context)
+}
+
+func (s vanishingDeltaSetImp) GetDelta(ctx context.Context, watermark string) (redblocks.Delta, error) {
+	if watermark != "" {
+		s.vanish(ctx)
+	}
+	return s.deltaSetImp.GetDelta(ctx, watermark)
+}
+
+func TestDeltaSetVanished(t *testing.T) {
+	stores := map[string]redblocks.Store{
+		"memory": redblocks.NewMemoryStore(),
+		"redis":  redblocks.NewRedisStore(newPool()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var watermarks []string
+			var set redblocks.ComposedSet
+			set = redblocks.Compose(vanishingDeltaSetImp{
+				deltaSetImp: deltaSetImp{
+					scoredSetImp: scoredSetImp{name: "TestDeltaSetVanished:" + name},
+					deltas: map[string]redblocks.Delta{
+						"":   {Added: []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}, Watermark: "v1"},
+						"v1": {Added: []redblocks.IDWithScore{{ID: "3", Score: 3}}, Watermark: "v2"},
+					},
+					watermarks: &watermarks,
+				},
+				vanish: func(ctx context.Context) {
+					if err := store.Delete(ctx, set.Key()); err != nil {
+						t.Error(err)
+					}
+				},
+			}, store)
+			if err := store.SetWatermark(ctx, set.Key(), "", 0); err != nil {
+				t.Error(err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := set.Update(ctx); err != nil {
+					t.Error(err)
+				}
+			}
+
+			// Rebuilt instead of keeping only the delta
+			idsWithScore, err := store.GetIDsWithScore(ctx, set.Key(), 0, -1, redblocks.Asc)
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(idsWithScore, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}); diff != "" {
+				t.Errorf(diff)
+			}
+			if diff := cmp.Diff(watermarks, []string{"", "v1", ""}); diff != "" {
+				t.Errorf(diff)
+			}
+			watermark, ok, err := store.Watermark(ctx, set.Key())
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff([]interface{}{watermark, ok}, []interface{}{"v1", true}); diff != "" {
+				t.Errorf(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package redblocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Errors returned by stores. Use errors.Is to check them, since they are wrapped with details.
+var (
+	ErrKeyNotFound      = errors.New("Not found")
+	ErrNoExpiry         = errors.New("Not configured expire")
+	ErrInvalidOrder     = errors.New("Undefined order passed")
+	ErrStoreUnavailable = errors.New("Store unavailable")
+)
+
+// ErrUnsupportedFailurePolicy is returned by Update of intersection and subtraction with SkipFailedChildren.
+var ErrUnsupportedFailurePolicy = errors.New("Failure policy is only supported by union")
+
+// CanceledError is returned by stores when ctx is canceled or its deadline is exceeded.
+type CanceledError struct {
+	Err error // context.Canceled or context.DeadlineExceeded
+}
+
+func (e *CanceledError) Error() string {
+	return "redblocks: " + e.Err.Error()
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// IsCanceled reports whether err is a CanceledError.
+func IsCanceled(err error) bool {
+	var canceledErr *CanceledError
+	return errors.As(err, &canceledErr)
+}
+
+// WarmupError is returned by Update of union, intersection and subtraction when children fail to warm up.
+type WarmupError struct {
+	Errs map[string]error // Keyed by the key of the child
+}
+
+func (e *WarmupError) Error() string {
+	keys := make([]string, 0, len(e.Errs))
+	for key := range e.Errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, len(keys), len(keys))
+	for i, key := range keys {
+		msgs[i] = key + ": " + e.Errs[key].Error()
+	}
+	return "redblocks: failed to warm up children: " + strings.Join(msgs, "; ")
+}
+
+func (e *WarmupError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// canceled replaces err by CanceledError when ctx is done
+func canceled(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return &CanceledError{Err: ctxErr}
+	}
+	return err
+}
+
+// storeErr classifies err returned by a Redis client into CanceledError or ErrStoreUnavailable
+func storeErr(ctx context.Context, err error) error {
+	err = canceled(ctx, err)
+	if err == nil || IsCanceled(err) {
+		return err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("%w: %w", ErrStoreUnavailable, err)
+	}
+	return err
+}
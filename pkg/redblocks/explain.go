@@ -0,0 +1,152 @@
+package redblocks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+// Operation describes how a set is derived from its children.
+type Operation struct {
+	Operator  string // union, intersection, subtraction, transform, top, filter, alias or leaf
+	Weights   []float64
+	Aggregate string
+	Params    []string // e.g. transforms of transform
+}
+
+// Describer is implemented by sets which are not leaves.
+type Describer interface {
+	Describe() Operation
+}
+
+// Describe returns the operation of set. Sets which do not implement Describer are leaves.
+func Describe(set interface{}) Operation {
+	if d, ok := set.(Describer); ok {
+		return d.Describe()
+	}
+	return Operation{Operator: "leaf"}
+}
+
+// StoreHolder is implemented by composed sets to expose the store they are saved in.
+type StoreHolder interface {
+	Store() Store
+}
+
+// Plan is a node of the tree returned by Explain.
+type Plan struct {
+	Key          string        `json:"key"`
+	CanonicalKey string        `json:"canonical_key,omitempty"` // Set only when Key is hashed
+	Operator     string        `json:"operator"`
+	Weights      []float64     `json:"weights,omitempty"`
+	Aggregate    string        `json:"aggregate,omitempty"`
+	Params       []string      `json:"params,omitempty"`
+	Exists       bool          `json:"exists"`
+	TTL          time.Duration `json:"-"` // -1 when the key does not exist or has no expiry
+	Available    bool          `json:"available"`
+	Count        int64         `json:"count"`
+	Children     []*Plan       `json:"children,omitempty"`
+}
+
+// MarshalJSON writes TTL in seconds, -1 when there is no TTL.
+func (p Plan) MarshalJSON() ([]byte, error) {
+	type plan Plan
+	ttl := p.TTL.Seconds()
+	if p.TTL < 0 {
+		ttl = -1
+	}
+	return json.Marshal(struct {
+		plan
+		TTL float64 `json:"ttl"`
+	}{plan: plan(p), TTL: ttl})
+}
+
+// String formats the tree with one node per line, children indented.
+func (p *Plan) String() string {
+	var b strings.Builder
+	p.write(&b, 0)
+	return b.String()
+}
+
+func (p *Plan) write(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	b.WriteString(p.Operator)
+	if p.Aggregate != "" {
+		b.WriteString(" aggregate=" + p.Aggregate)
+	}
+	if len(p.Weights) > 0 {
+		ws := make([]string, len(p.Weights), len(p.Weights))
+		for i, w := range p.Weights {
+			ws[i] = strconv.FormatFloat(w, 'g', -1, 64)
+		}
+		b.WriteString(" weights=" + strings.Join(ws, ","))
+	}
+	if len(p.Params) > 0 {
+		b.WriteString(" params=" + strings.Join(p.Params, ","))
+	}
+	fmt.Fprintf(b, " key=%q exists=%v ttl=%v available=%v count=%d\n", p.Key, p.Exists, p.TTL, p.Available, p.Count)
+	for _, child := range p.Children {
+		child.write(b, depth+1)
+	}
+}
+
+// Explain walks the tree of set and reports the state of each node. It does not update any set.
+func Explain(ctx context.Context, set ComposedSet) (*Plan, error) {
+	op := Describe(set)
+	plan := &Plan{
+		Key:       set.Key(),
+		Operator:  op.Operator,
+		Weights:   op.Weights,
+		Aggregate: op.Aggregate,
+		Params:    op.Params,
+		TTL:       -1,
+	}
+	if canonical := CanonicalKey(set); canonical != plan.Key {
+		plan.CanonicalKey = canonical
+	}
+
+	available, err := set.Available(ctx)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	plan.Available = available
+
+	if holder, ok := set.(StoreHolder); ok {
+		store := holder.Store()
+		exists, err := store.Exists(ctx, plan.Key)
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		plan.Exists = exists
+
+		if exists {
+			ttl, err := store.TTL(ctx, plan.Key)
+			switch {
+			case err == nil:
+				plan.TTL = ttl
+			case !errors.Is(err, ErrNoExpiry) && !errors.Is(err, ErrKeyNotFound):
+				return nil, fail.Wrap(err)
+			}
+
+			plan.Count, err = store.Count(ctx, plan.Key)
+			if err != nil {
+				return nil, fail.Wrap(err)
+			}
+		}
+	}
+
+	for _, child := range Children(set) {
+		childPlan, err := Explain(ctx, child)
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		plan.Children = append(plan.Children, childPlan)
+	}
+
+	return plan, nil
+}
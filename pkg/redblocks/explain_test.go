@@ -0,0 +1,64 @@
+package redblocks_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func TestExplain(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	ctx := context.Background()
+	tokyo := redblocks.Compose(NewScoredSet("tokyo", []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}), store)
+	osaka := redblocks.Compose(NewScoredSet("osaka", []redblocks.IDWithScore{{ID: "2", Score: 3}}), store)
+	union := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Max, tokyo, osaka)
+
+	if err := tokyo.Warmup(ctx); err != nil {
+		t.Error(err)
+	}
+
+	plan, err := redblocks.Explain(ctx, union)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ignoreTTL := cmpopts.IgnoreFields(redblocks.Plan{}, "TTL", "Available")
+	expected := &redblocks.Plan{
+		Key:       union.Key(),
+		Operator:  "union",
+		Weights:   []float64{1, 1},
+		Aggregate: "MAX",
+		Children: []*redblocks.Plan{
+			{Key: tokyo.Key(), Operator: "leaf", Exists: true, Count: 2},
+			{Key: osaka.Key(), Operator: "leaf"},
+		},
+	}
+	if diff := cmp.Diff(plan, expected, ignoreTTL); diff != "" {
+		t.Errorf(diff)
+	}
+	if plan.TTL != -1 || plan.Children[0].TTL <= 0 {
+		t.Errorf("unexpected ttl: %v, %v", plan.TTL, plan.Children[0].TTL)
+	}
+
+	if !strings.HasPrefix(plan.String(), "union aggregate=MAX weights=1,1 key=") {
+		t.Errorf("unexpected text: %s", plan.String())
+	}
+
+	b, err := json.Marshal(plan)
+	if err != nil {
+		t.Error(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff([]interface{}{decoded["operator"], decoded["ttl"], len(decoded["children"].([]interface{}))}, []interface{}{"union", float64(-1), 2}); diff != "" {
+		t.Errorf(diff)
+	}
+}
@@ -0,0 +1,318 @@
+package redblocks
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Registry resolves set names used in expressions
+type Registry map[string]ComposedSet
+
+// SyntaxError is returned by NewExpressionSet. Pos is the byte offset in the expression.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d: %s", e.Pos, e.Msg)
+}
+
+// NewExpressionSet builds a ComposedSet from an expression such as `(tokyo & premium) | osaka - banned`.
+//
+//	a & b       intersection
+//	a | b       union
+//	a - b       subtraction, keeps scores of a
+//	a*2         weight of a in the enclosing union or intersection
+//	a |[max] b  aggregate of the union or intersection. min, max or sum (default)
+//
+// & binds tighter than | and -, which are left associative. So `a | b - c` is `(a | b) - c`.
+// Sequences of the same operator become one set, e.g. `a | b | c` is one union of three sets.
+// Composed sets use cacheTime and notAvailableTTL.
+func NewExpressionSet(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, registry Registry, expr string) (ComposedSet, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &expressionParser{
+		store:           store,
+		cacheTime:       cacheTime,
+		notAvailableTTL: notAvailableTTL,
+		registry:        registry,
+		tokens:          tokens,
+	}
+	operand, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	if operand.weighted {
+		return nil, &SyntaxError{Pos: operand.pos, Msg: "weight is only allowed in | or &"}
+	}
+
+	return operand.set, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(expr string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.IndexByte("|&-()*[]", c) >= 0:
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(c), pos: i})
+			i++
+		case isIdentByte(c):
+			start := i
+			for i < len(expr) && isIdentByte(expr[i]) {
+				i++
+			}
+			kind := tokenIdent
+			if isNumber(expr[start:i]) {
+				kind = tokenNumber
+			}
+			tokens = append(tokens, token{kind: kind, text: expr[start:i], pos: start})
+		default:
+			return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, text: "end of expression", pos: len(expr)}), nil
+}
+
+// isNumber reports whether text is a finite decimal literal.
+// ParseFloat also accepts nan, inf, hex floats and underscores, which are left as names.
+func isNumber(text string) bool {
+	if strings.Trim(text, "0123456789.eE") != "" {
+		return false
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	return err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+func isIdentByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == ':' || c == '.'
+}
+
+type expressionParser struct {
+	store           Store
+	cacheTime       time.Duration
+	notAvailableTTL time.Duration
+	registry        Registry
+	tokens          []token
+	i               int
+}
+
+type operand struct {
+	set      ComposedSet
+	weight   float64
+	weighted bool
+	pos      int
+}
+
+// pending is a sequence of the same operator which is not built yet
+type pending struct {
+	op        string
+	aggregate Aggregate
+	operands  []operand
+}
+
+func (p *expressionParser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *expressionParser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *expressionParser) expect(text string) error {
+	if tok := p.next(); tok.kind != tokenSymbol || tok.text != text {
+		return &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("expected %q but got %q", text, tok.text)}
+	}
+	return nil
+}
+
+// expr := term (('|' aggregate? | '-') term)*
+func (p *expressionParser) parseExpr() (operand, error) {
+	return p.parseChain([]string{"|", "-"}, p.parseTerm)
+}
+
+// term := factor ('&' aggregate? factor)*
+func (p *expressionParser) parseTerm() (operand, error) {
+	return p.parseChain([]string{"&"}, p.parseFactor)
+}
+
+func (p *expressionParser) parseChain(ops []string, parseOperand func() (operand, error)) (operand, error) {
+	left, err := parseOperand()
+	if err != nil {
+		return operand{}, err
+	}
+
+	var cur *pending
+	for {
+		tok := p.peek()
+		if tok.kind != tokenSymbol || !contains(ops, tok.text) {
+			break
+		}
+		p.next()
+
+		aggregate := Aggregate(Sum)
+		if tok.text != "-" && p.peek().text == "[" {
+			aggregate, err = p.parseAggregate()
+			if err != nil {
+				return operand{}, err
+			}
+		}
+
+		right, err := parseOperand()
+		if err != nil {
+			return operand{}, err
+		}
+
+		if cur != nil && cur.op == tok.text && cur.aggregate == aggregate {
+			cur.operands = append(cur.operands, right)
+			continue
+		}
+		if cur != nil {
+			left, err = p.build(cur)
+			if err != nil {
+				return operand{}, err
+			}
+		}
+		cur = &pending{op: tok.text, aggregate: aggregate, operands: []operand{left, right}}
+	}
+
+	if cur == nil {
+		return left, nil
+	}
+	return p.build(cur)
+}
+
+// aggregate := '[' ('min' | 'max' | 'sum') ']'
+func (p *expressionParser) parseAggregate() (Aggregate, error) {
+	if err := p.expect("["); err != nil {
+		return 0, err
+	}
+	tok := p.next()
+	var aggregate Aggregate
+	switch strings.ToLower(tok.text) {
+	case "min":
+		aggregate = Min
+	case "max":
+		aggregate = Max
+	case "sum":
+		aggregate = Sum
+	default:
+		return 0, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unknown aggregate %q", tok.text)}
+	}
+	if err := p.expect("]"); err != nil {
+		return 0, err
+	}
+	return aggregate, nil
+}
+
+// factor := primary ('*' number)?
+func (p *expressionParser) parseFactor() (operand, error) {
+	o, err := p.parsePrimary()
+	if err != nil {
+		return operand{}, err
+	}
+	if p.peek().text != "*" {
+		return o, nil
+	}
+	p.next()
+
+	tok := p.next()
+	if tok.kind != tokenNumber {
+		return operand{}, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("expected weight but got %q", tok.text)}
+	}
+	weight, _ := strconv.ParseFloat(tok.text, 64)
+	o.weight = weight
+	o.weighted = true
+	return o, nil
+}
+
+// primary := name | '(' expr ')'
+func (p *expressionParser) parsePrimary() (operand, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokenIdent || tok.kind == tokenNumber:
+		set, ok := p.registry[tok.text]
+		if !ok {
+			return operand{}, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unknown set %q", tok.text)}
+		}
+		return operand{set: set, weight: 1, pos: tok.pos}, nil
+	case tok.kind == tokenSymbol && tok.text == "(":
+		o, err := p.parseExpr()
+		if err != nil {
+			return operand{}, err
+		}
+		if o.weighted {
+			return operand{}, &SyntaxError{Pos: o.pos, Msg: "weight is only allowed in | or &"}
+		}
+		if err := p.expect(")"); err != nil {
+			return operand{}, err
+		}
+		o.pos = tok.pos
+		return o, nil
+	default:
+		return operand{}, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("expected set name or \"(\" but got %q", tok.text)}
+	}
+}
+
+func (p *expressionParser) build(cur *pending) (operand, error) {
+	sets := make([]ComposedSet, len(cur.operands), len(cur.operands))
+	weights := make([]float64, len(cur.operands), len(cur.operands))
+	for i, o := range cur.operands {
+		if cur.op == "-" && o.weighted {
+			return operand{}, &SyntaxError{Pos: o.pos, Msg: "weight is only allowed in | or &"}
+		}
+		sets[i] = o.set
+		weights[i] = o.weight
+	}
+
+	var set ComposedSet
+	switch cur.op {
+	case "|":
+		set = NewUnionSet(p.store, p.cacheTime, p.notAvailableTTL, weights, cur.aggregate, sets...)
+	case "&":
+		set = NewIntersectionSet(p.store, p.cacheTime, p.notAvailableTTL, weights, cur.aggregate, sets...)
+	case "-":
+		set = NewSubtractionSet(p.store, p.cacheTime, p.notAvailableTTL, sets[0], sets[1:]...)
+	}
+	return operand{set: set, weight: 1, pos: cur.operands[0].pos}, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
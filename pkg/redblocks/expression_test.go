@@ -0,0 +1,133 @@
+package redblocks_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func newExpressionRegistry(store redblocks.Store) redblocks.Registry {
+	return redblocks.Registry{
+		"tokyo":   redblocks.Compose(NewScoredSet("tokyo", []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}, {ID: "3", Score: 3}}), store),
+		"premium": redblocks.Compose(NewScoredSet("premium", []redblocks.IDWithScore{{ID: "2", Score: 10}, {ID: "3", Score: 10}, {ID: "5", Score: 10}}), store),
+		"osaka":   redblocks.Compose(NewScoredSet("osaka", []redblocks.IDWithScore{{ID: "4", Score: 4}, {ID: "5", Score: 5}}), store),
+		"banned":  redblocks.Compose(NewScoredSet("banned", []redblocks.IDWithScore{{ID: "3", Score: 100}}), store),
+	}
+}
+
+func TestExpressionSet(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		expr string
+		want []redblocks.IDWithScore
+	}{
+		{
+			expr: "(tokyo & premium) | osaka - banned",
+			want: []redblocks.IDWithScore{{ID: "4", Score: 4}, {ID: "5", Score: 5}, {ID: "2", Score: 12}},
+		},
+		{
+			expr: "tokyo | osaka | banned",
+			want: []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}, {ID: "4", Score: 4}, {ID: "5", Score: 5}, {ID: "3", Score: 103}},
+		},
+		{
+			expr: "tokyo*2 &[max] premium*0.5",
+			want: []redblocks.IDWithScore{{ID: "2", Score: 5}, {ID: "3", Score: 6}},
+		},
+		{
+			expr: "tokyo | premium & osaka",
+			want: []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}, {ID: "3", Score: 3}, {ID: "5", Score: 15}},
+		},
+		{
+			expr: "tokyo - banned - premium",
+			want: []redblocks.IDWithScore{{ID: "1", Score: 1}},
+		},
+	}
+
+	for _, test := range tests {
+		store := redblocks.NewMemoryStore()
+		registry := newExpressionRegistry(store)
+		set, err := redblocks.NewExpressionSet(store, time.Second*100, time.Second*10, registry, test.expr)
+		if err != nil {
+			t.Errorf("%v: %v", test.expr, err)
+			continue
+		}
+		result, err := set.IDsWithScore(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff(result, test.want); diff != "" {
+			t.Errorf("%v: %v", test.expr, diff)
+		}
+	}
+}
+
+func TestExpressionSetSyntaxError(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	registry := newExpressionRegistry(store)
+
+	tests := []struct {
+		expr string
+		want redblocks.SyntaxError
+	}{
+		{
+			expr: "(tokyo & premium",
+			want: redblocks.SyntaxError{Pos: 16, Msg: `expected ")" but got "end of expression"`},
+		},
+		{
+			expr: "tokyo | nagoya",
+			want: redblocks.SyntaxError{Pos: 8, Msg: `unknown set "nagoya"`},
+		},
+		{
+			expr: "tokyo |[avg] osaka",
+			want: redblocks.SyntaxError{Pos: 8, Msg: `unknown aggregate "avg"`},
+		},
+		{
+			expr: "tokyo - banned*2",
+			want: redblocks.SyntaxError{Pos: 8, Msg: "weight is only allowed in | or &"},
+		},
+		{
+			expr: "tokyo $ osaka",
+			want: redblocks.SyntaxError{Pos: 6, Msg: `unexpected character '$'`},
+		},
+		{
+			expr: "tokyo osaka",
+			want: redblocks.SyntaxError{Pos: 6, Msg: `unexpected "osaka"`},
+		},
+		{
+			expr: "tokyo | osaka*nan",
+			want: redblocks.SyntaxError{Pos: 14, Msg: `expected weight but got "nan"`},
+		},
+		{
+			expr: "tokyo | osaka*inf",
+			want: redblocks.SyntaxError{Pos: 14, Msg: `expected weight but got "inf"`},
+		},
+		{
+			expr: "tokyo | osaka*0x1p3",
+			want: redblocks.SyntaxError{Pos: 14, Msg: `expected weight but got "0x1p3"`},
+		},
+		{
+			expr: "tokyo | osaka*1_0",
+			want: redblocks.SyntaxError{Pos: 14, Msg: `expected weight but got "1_0"`},
+		},
+		{
+			expr: "tokyo | osaka*1e999",
+			want: redblocks.SyntaxError{Pos: 14, Msg: `expected weight but got "1e999"`},
+		},
+	}
+
+	for _, test := range tests {
+		_, err := redblocks.NewExpressionSet(store, time.Second*100, time.Second*10, registry, test.expr)
+		syntaxErr, ok := err.(*redblocks.SyntaxError)
+		if !ok {
+			t.Errorf("%v: want *SyntaxError but got %v", test.expr, err)
+			continue
+		}
+		if diff := cmp.Diff(*syntaxErr, test.want); diff != "" {
+			t.Errorf("%v: %v", test.expr, diff)
+		}
+	}
+}
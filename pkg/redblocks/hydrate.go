@@ -0,0 +1,191 @@
+package redblocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+// Resolver loads entities of ids in one batch, e.g. from MySQL or a service.
+// IDs which no longer exist are left out of the result.
+type Resolver[T any] interface {
+	Resolve(ctx context.Context, ids []ID) (map[ID]T, error)
+}
+
+// ResolverFunc is a function implementing Resolver.
+type ResolverFunc[T any] func(ctx context.Context, ids []ID) (map[ID]T, error)
+
+func (f ResolverFunc[T]) Resolve(ctx context.Context, ids []ID) (map[ID]T, error) {
+	return f(ctx, ids)
+}
+
+// NewMemoResolver returns Resolver which loads each ID by resolver at most once within a ctx from WithMemo, including IDs which did not resolve.
+// The memo belongs to the ctx, so a resolver can be shared by requests and each request starts with an empty memo.
+// Without WithMemo, every call is passed to resolver.
+func NewMemoResolver[T any](resolver Resolver[T]) Resolver[T] {
+	return &memoResolver[T]{resolver: resolver}
+}
+
+type memoResolver[T any] struct {
+	resolver Resolver[T]
+}
+
+type memoScopeKey struct{}
+
+type memoScope struct {
+	mu    sync.Mutex
+	memos map[interface{}]interface{}
+}
+
+// WithMemo returns a copy of ctx which keeps results of resolvers from NewMemoResolver until it is discarded, e.g. per request.
+func WithMemo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, memoScopeKey{}, &memoScope{memos: map[interface{}]interface{}{}})
+}
+
+type memo[T any] struct {
+	mu       sync.Mutex
+	resolved map[ID]T
+	missing  map[ID]struct{}
+}
+
+func (r *memoResolver[T]) memo(ctx context.Context) (*memo[T], bool) {
+	scope, ok := ctx.Value(memoScopeKey{}).(*memoScope)
+	if !ok {
+		return nil, false
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	if m, ok := scope.memos[r]; ok {
+		return m.(*memo[T]), true
+	}
+	m := &memo[T]{resolved: map[ID]T{}, missing: map[ID]struct{}{}}
+	scope.memos[r] = m
+	return m, true
+}
+
+func (r *memoResolver[T]) Resolve(ctx context.Context, ids []ID) (map[ID]T, error) {
+	m, ok := r.memo(ctx)
+	if !ok {
+		loaded, err := r.resolver.Resolve(ctx, ids)
+		return loaded, fail.Wrap(err)
+	}
+
+	result := make(map[ID]T, len(ids))
+	var todo []ID
+	m.mu.Lock()
+	for _, id := range ids {
+		if v, ok := m.resolved[id]; ok {
+			result[id] = v
+			continue
+		}
+		if _, ok := m.missing[id]; ok {
+			continue
+		}
+		todo = append(todo, id)
+	}
+	m.mu.Unlock()
+
+	if len(todo) == 0 {
+		return result, nil
+	}
+	loaded, err := r.resolver.Resolve(ctx, todo)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range todo {
+		if v, ok := loaded[id]; ok {
+			m.resolved[id] = v
+			result[id] = v
+			continue
+		}
+		m.missing[id] = struct{}{}
+	}
+	return result, nil
+}
+
+// Hydrated is a member of a set with its entity.
+type Hydrated[T any] struct {
+	ID     ID
+	Score  float64
+	Entity T
+}
+
+// HydratedSet is ComposedSet which also returns entities of its members.
+type HydratedSet[T any] interface {
+	ComposedSet
+	// Hydrate returns up to count entities after cursor in score order, like IDsWithScoreWithCursor.
+	// IDs which do not resolve are handled by MissingPolicy.
+	Hydrate(ctx context.Context, cursor Cursor, count int64, opts ...PagenationOption) ([]Hydrated[T], Cursor, error)
+}
+
+func NewHydratedSet[T any](set ComposedSet, resolver Resolver[T], opts ...HydrateOption) HydratedSet[T] {
+	opt, _ := HydrateOptionsToHydrateOption(opts)
+	return hydratedSetImp[T]{
+		ComposedSet: set,
+		resolver:    resolver,
+		opt:         opt,
+	}
+}
+
+type hydratedSetImp[T any] struct {
+	ComposedSet
+	resolver Resolver[T]
+	opt      HydrateOption
+}
+
+func (s hydratedSetImp[T]) Hydrate(ctx context.Context, cursor Cursor, count int64, opts ...PagenationOption) ([]Hydrated[T], Cursor, error) {
+	result := make([]Hydrated[T], 0, count)
+	for refills := 0; ; refills++ {
+		idsWithScore, next, err := s.IDsWithScoreWithCursor(ctx, cursor, count-int64(len(result)), opts...)
+		if err != nil {
+			return nil, "", fail.Wrap(err)
+		}
+		cursor = next
+
+		ids := make([]ID, len(idsWithScore), len(idsWithScore))
+		for i, v := range idsWithScore {
+			ids[i] = v.ID
+		}
+		entities, err := s.resolver.Resolve(ctx, ids)
+		if err != nil {
+			return nil, "", fail.Wrap(err)
+		}
+		for _, v := range idsWithScore {
+			if entity, ok := entities[v.ID]; ok {
+				result = append(result, Hydrated[T]{ID: v.ID, Score: v.Score, Entity: entity})
+			}
+		}
+
+		full := int64(len(result)) >= count
+		if full || cursor == "" || s.opt.MissingPolicy == DropMissing || refills >= s.opt.MaxRefills {
+			return result, cursor, nil
+		}
+	}
+}
+
+func (s hydratedSetImp[T]) Children() []ComposedSet {
+	return Children(s.ComposedSet)
+}
+
+func (s hydratedSetImp[T]) CanonicalKey() string {
+	return CanonicalKey(s.ComposedSet)
+}
+
+func (s hydratedSetImp[T]) KeyPrefix() string {
+	return KeyPrefix(s.ComposedSet)
+}
+
+func (s hydratedSetImp[T]) Describe() Operation {
+	return Describe(s.ComposedSet)
+}
+
+func (s hydratedSetImp[T]) Store() Store {
+	if holder, ok := s.ComposedSet.(StoreHolder); ok {
+		return holder.Store()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package redblocks_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+type user struct {
+	Name string
+}
+
+func TestHydratedSet(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	ctx := redblocks.WithMemo(context.Background())
+	set := redblocks.Compose(NewScoredSet("TestHydratedSet", []redblocks.IDWithScore{
+		{ID: "1", Score: 1}, {ID: "2", Score: 2}, {ID: "3", Score: 3}, {ID: "4", Score: 4}, {ID: "5", Score: 5},
+	}), store)
+
+	users := map[redblocks.ID]user{"1": {Name: "a"}, "3": {Name: "c"}, "4": {Name: "d"}, "5": {Name: "e"}}
+	var loaded [][]redblocks.ID
+	resolver := redblocks.NewMemoResolver[user](redblocks.ResolverFunc[user](func(ctx context.Context, ids []redblocks.ID) (map[redblocks.ID]user, error) {
+		loaded = append(loaded, ids)
+		r := map[redblocks.ID]user{}
+		for _, id := range ids {
+			if u, ok := users[id]; ok {
+				r[id] = u
+			}
+		}
+		return r, nil
+	}))
+
+	hydrated := redblocks.NewHydratedSet[user](set, resolver)
+	page, cursor, err := hydrated.Hydrate(ctx, "", 2)
+	if err != nil {
+		t.Error(err)
+	}
+	// 2 does not resolve, so 3 is loaded to fill the page
+	if diff := cmp.Diff(page, []redblocks.Hydrated[user]{{ID: "1", Score: 1, Entity: user{Name: "a"}}, {ID: "3", Score: 3, Entity: user{Name: "c"}}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	page, _, err = hydrated.Hydrate(ctx, cursor, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(page, []redblocks.Hydrated[user]{{ID: "4", Score: 4, Entity: user{Name: "d"}}, {ID: "5", Score: 5, Entity: user{Name: "e"}}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	// Already loaded IDs are memoized, including 2 which did not resolve
+	dropped := redblocks.NewHydratedSet[user](set, resolver, redblocks.WithMissingPolicy(redblocks.DropMissing))
+	page, _, err = dropped.Hydrate(ctx, "", 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(page, []redblocks.Hydrated[user]{{ID: "1", Score: 1, Entity: user{Name: "a"}}}); diff != "" {
+		t.Errorf(diff)
+	}
+	if diff := cmp.Diff(loaded, [][]redblocks.ID{{"1", "2"}, {"3"}, {"4", "5"}}); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestMemoResolverScope(t *testing.T) {
+	var loaded [][]redblocks.ID
+	resolver := redblocks.NewMemoResolver[user](redblocks.ResolverFunc[user](func(ctx context.Context, ids []redblocks.ID) (map[redblocks.ID]user, error) {
+		loaded = append(loaded, ids)
+		return map[redblocks.ID]user{"1": {Name: "a"}}, nil
+	}))
+
+	// Each request has its own memo, and no memo without WithMemo
+	for _, ctx := range []context.Context{redblocks.WithMemo(context.Background()), redblocks.WithMemo(context.Background()), context.Background()} {
+		for i := 0; i < 2; i++ {
+			r, err := resolver.Resolve(ctx, []redblocks.ID{"1"})
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(r, map[redblocks.ID]user{"1": {Name: "a"}}); diff != "" {
+				t.Errorf(diff)
+			}
+		}
+	}
+	if diff := cmp.Diff(loaded, [][]redblocks.ID{{"1"}, {"1"}, {"1"}, {"1"}}); diff != "" {
+		t.Errorf(diff)
+	}
+}
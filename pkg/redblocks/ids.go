@@ -3,7 +3,7 @@ package redblocks
 import (
 	"context"
 
-	"github.com/srvc/fail"
+	"github.com/rerost/redblocks-go/internal/fail"
 )
 
 type WithIDs = ComposedSet
@@ -23,15 +23,20 @@ func (c withIDsImp) IDs(ctx context.Context, opts ...PagenationOption) ([]ID, er
 		return []ID{}, fail.Wrap(err)
 	}
 
-	exists, err := c.store.Exists(ctx, c.Key())
-	if err != nil {
-		return []ID{}, fail.Wrap(err)
+	if opt.ByScore && opt.Count == 0 {
+		return []ID{}, nil
 	}
-	if !exists {
-		c.Warmup(ctx)
+
+	if err := c.warmup(ctx); err != nil {
+		return []ID{}, fail.Wrap(err)
 	}
 
-	r, err := c.store.GetIDs(ctx, c.Key(), opt.Head, opt.Tail, opt.Order)
+	var r []ID
+	if opt.ByScore {
+		r, err = c.store.GetIDsByScore(ctx, c.Key(), opt.Min, opt.Max, opt.Offset, opt.Count, opt.Order)
+	} else {
+		r, err = c.store.GetIDs(ctx, c.Key(), opt.Head, opt.Tail, opt.Order)
+	}
 	if err != nil {
 		return []ID{}, fail.Wrap(err)
 	}
@@ -45,15 +50,20 @@ func (c withIDsImp) IDsWithScore(ctx context.Context, opts ...PagenationOption)
 		return []IDWithScore{}, fail.Wrap(err)
 	}
 
-	exists, err := c.store.Exists(ctx, c.Key())
-	if err != nil {
-		return []IDWithScore{}, fail.Wrap(err)
+	if opt.ByScore && opt.Count == 0 {
+		return []IDWithScore{}, nil
 	}
-	if !exists {
-		c.Warmup(ctx)
+
+	if err := c.warmup(ctx); err != nil {
+		return []IDWithScore{}, fail.Wrap(err)
 	}
 
-	r, err := c.store.GetIDsWithScore(ctx, c.Key(), opt.Head, opt.Tail, opt.Order)
+	var r []IDWithScore
+	if opt.ByScore {
+		r, err = c.store.GetIDsWithScoreByScore(ctx, c.Key(), opt.Min, opt.Max, opt.Offset, opt.Count, opt.Order)
+	} else {
+		r, err = c.store.GetIDsWithScore(ctx, c.Key(), opt.Head, opt.Tail, opt.Order)
+	}
 	if err != nil {
 		return []IDWithScore{}, fail.Wrap(err)
 	}
@@ -61,6 +71,103 @@ func (c withIDsImp) IDsWithScore(ctx context.Context, opts ...PagenationOption)
 	return r, nil
 }
 
+// IDsWithCursor returns at most count IDs after cursor and the cursor for the next page.
+// Use empty cursor for the first page. Head and Tail of opts are ignored.
+func (c withIDsImp) IDsWithCursor(ctx context.Context, cursor Cursor, count int64, opts ...PagenationOption) ([]ID, Cursor, error) {
+	idsWithScore, next, err := c.IDsWithScoreWithCursor(ctx, cursor, count, opts...)
+	if err != nil {
+		return []ID{}, "", fail.Wrap(err)
+	}
+
+	ids := make([]ID, len(idsWithScore), len(idsWithScore))
+	for i, idWithScore := range idsWithScore {
+		ids[i] = idWithScore.ID
+	}
+	return ids, next, nil
+}
+
+func (c withIDsImp) IDsWithScoreWithCursor(ctx context.Context, cursor Cursor, count int64, opts ...PagenationOption) ([]IDWithScore, Cursor, error) {
+	opt, err := PagenationOptionsToPagenationOption(opts)
+	if err != nil {
+		return []IDWithScore{}, "", fail.Wrap(err)
+	}
+	if count <= 0 {
+		return []IDWithScore{}, "", fail.Wrap(fail.New("count must be positive"), fail.WithParam("count", count))
+	}
+
+	min, max := opt.Min, opt.Max
+	var last IDWithScore
+	var ties int64
+	if cursor != "" {
+		last, ties, err = cursor.decode()
+		if err != nil {
+			return []IDWithScore{}, "", fail.Wrap(err)
+		}
+		if opt.Order == Desc && last.Score < max.Score {
+			max = Inclusive(last.Score)
+		}
+		if opt.Order != Desc && last.Score > min.Score {
+			min = Inclusive(last.Score)
+		}
+	}
+
+	if err := c.warmup(ctx); err != nil {
+		return []IDWithScore{}, "", fail.Wrap(err)
+	}
+
+	// The range starts at the score of the cursor, where the cursor is preceded by ties-1 members unless they are changed
+	var offset int64
+	if ties > 1 && (opt.Order == Desc && max == Inclusive(last.Score) || opt.Order != Desc && min == Inclusive(last.Score)) {
+		offset = ties - 1
+	}
+	confirm := offset > 0
+
+	// Members which have the same score as the cursor are fetched again, so skip until the cursor
+	r := make([]IDWithScore, 0, count)
+	for int64(len(r)) < count {
+		limit := count
+		if confirm {
+			// One more for the cursor itself
+			limit++
+		}
+		batch, err := c.store.GetIDsWithScoreByScore(ctx, c.Key(), min, max, offset, limit, opt.Order)
+		if err != nil {
+			return []IDWithScore{}, "", fail.Wrap(err)
+		}
+		if confirm {
+			confirm = false
+			// Members before the cursor are changed, so skip them from the beginning of the range
+			if len(batch) == 0 || batch[0] != last {
+				offset = 0
+				continue
+			}
+		}
+		for _, idWithScore := range batch {
+			if cursor != "" && !after(idWithScore, last, opt.Order) {
+				continue
+			}
+			r = append(r, idWithScore)
+			if int64(len(r)) == count {
+				break
+			}
+		}
+		if int64(len(batch)) < limit {
+			break
+		}
+		offset += int64(len(batch))
+	}
+
+	var next Cursor
+	if int64(len(r)) == count {
+		var before int64
+		if cursor != "" && r[0].Score == last.Score {
+			before = ties
+		}
+		next = newCursor(r[len(r)-1], countTies(r, before))
+	}
+	return r, next, nil
+}
+
 func (c withIDsImp) Count(ctx context.Context) (int64, error) {
 	if err := c.Warmup(ctx); err != nil {
 		return 0, fail.Wrap(err)
@@ -69,3 +176,91 @@ func (c withIDsImp) Count(ctx context.Context) (int64, error) {
 	count, err := c.store.Count(ctx, c.Key())
 	return count, fail.Wrap(err)
 }
+
+func (c withIDsImp) CountByScore(ctx context.Context, min ScoreBound, max ScoreBound) (int64, error) {
+	if err := c.Warmup(ctx); err != nil {
+		return 0, fail.Wrap(err)
+	}
+
+	count, err := c.store.CountByScore(ctx, c.Key(), min, max)
+	return count, fail.Wrap(err)
+}
+
+func (c withIDsImp) Contains(ctx context.Context, ids ...ID) (map[ID]bool, error) {
+	scores, err := c.Score(ctx, ids...)
+	if err != nil {
+		return map[ID]bool{}, fail.Wrap(err)
+	}
+
+	contains := make(map[ID]bool, len(ids))
+	for _, id := range ids {
+		_, contains[id] = scores[id]
+	}
+	return contains, nil
+}
+
+func (c withIDsImp) Score(ctx context.Context, ids ...ID) (map[ID]float64, error) {
+	if err := c.warmup(ctx); err != nil {
+		return map[ID]float64{}, fail.Wrap(err)
+	}
+
+	scores, err := c.store.Scores(ctx, c.Key(), ids...)
+	if err != nil {
+		return map[ID]float64{}, fail.Wrap(err)
+	}
+	return scores, nil
+}
+
+// Rank returns the 0-based position of id in order. It returns false when id is not a member.
+func (c withIDsImp) Rank(ctx context.Context, id ID, order Order) (int64, bool, error) {
+	if err := c.warmup(ctx); err != nil {
+		return 0, false, fail.Wrap(err)
+	}
+
+	rank, ok, err := c.store.Rank(ctx, c.Key(), id, order)
+	if err != nil {
+		return 0, false, fail.Wrap(err)
+	}
+	return rank, ok, nil
+}
+
+// Invalidate deletes the set and sets derived from it, so they are rebuilt on the next read. See Invalidate.
+func (c withIDsImp) Invalidate(ctx context.Context) error {
+	return fail.Wrap(Invalidate(ctx, c.store, c.Key()))
+}
+
+func (c withIDsImp) warmup(ctx context.Context) error {
+	exists, err := c.store.Exists(ctx, c.Key())
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	if exists {
+		o, _ := observerOf(c.store)
+		o.CacheHit(ctx, c.Key())
+		return nil
+	}
+	return fail.Wrap(c.Warmup(ctx))
+}
+
+func (c withIDsImp) Children() []ComposedSet {
+	return Children(c.WithWarmup)
+}
+
+func (c withIDsImp) CanonicalKey() string {
+	if canonical, ok := c.WithWarmup.(Canonical); ok {
+		return canonical.CanonicalKey()
+	}
+	return c.Key()
+}
+
+func (c withIDsImp) KeyPrefix() string {
+	return KeyPrefix(c.WithWarmup)
+}
+
+func (c withIDsImp) Describe() Operation {
+	return Describe(c.WithWarmup)
+}
+
+func (c withIDsImp) Store() Store {
+	return c.store
+}
@@ -0,0 +1,45 @@
+package redblocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+// DependentsKey is the key where Redis backed stores keep keys of sets derived from key.
+func DependentsKey(key string) string {
+	return key + ":dependents"
+}
+
+// recordDependents records that key is derived from children, so invalidating a child also invalidates key.
+func recordDependents(ctx context.Context, store Store, key string, children []ComposedSet, expire time.Duration) error {
+	for _, child := range children {
+		if err := store.AddDependent(ctx, child.Key(), key, expire); err != nil {
+			return fail.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// Invalidate deletes key and all keys derived from it, which are recorded when composed sets are updated.
+// They are deleted at once, so a set is never rebuilt from a stale child.
+func Invalidate(ctx context.Context, store Store, key string) error {
+	keys := []string{key}
+	seen := map[string]struct{}{key: {}}
+	for i := 0; i < len(keys); i++ {
+		dependents, err := store.Dependents(ctx, keys[i])
+		if err != nil {
+			return fail.Wrap(err)
+		}
+		for _, dependent := range dependents {
+			if _, ok := seen[dependent]; ok {
+				continue
+			}
+			seen[dependent] = struct{}{}
+			keys = append(keys, dependent)
+		}
+	}
+
+	return fail.Wrap(store.Delete(ctx, keys...))
+}
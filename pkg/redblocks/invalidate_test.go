@@ -0,0 +1,83 @@
+package redblocks_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func TestInvalidate(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	ctx := context.Background()
+	tokyo := redblocks.Compose(NewScoredSet("tokyo", []redblocks.IDWithScore{{ID: "1", Score: 1}}), store)
+	osaka := redblocks.Compose(NewScoredSet("osaka", []redblocks.IDWithScore{{ID: "2", Score: 2}}), store)
+	banned := redblocks.Compose(NewScoredSet("banned", []redblocks.IDWithScore{{ID: "2", Score: 0}}), store)
+	union := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, tokyo, osaka)
+	subtracted := redblocks.NewSubtractionSet(store, time.Second*100, time.Second*10, union, banned)
+
+	if err := subtracted.Warmup(ctx); err != nil {
+		t.Error(err)
+	}
+
+	if err := tokyo.Invalidate(ctx); err != nil {
+		t.Error(err)
+	}
+
+	exists := map[string]bool{}
+	for _, set := range []redblocks.ComposedSet{tokyo, osaka, banned, union, subtracted} {
+		ok, err := store.Exists(ctx, set.Key())
+		if err != nil {
+			t.Error(err)
+		}
+		exists[set.Key()] = ok
+	}
+	expected := map[string]bool{tokyo.Key(): false, osaka.Key(): true, banned.Key(): true, union.Key(): false, subtracted.Key(): false}
+	if diff := cmp.Diff(exists, expected); diff != "" {
+		t.Errorf(diff)
+	}
+
+	ids, err := subtracted.IDs(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"1"}); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestStoreAddDependent(t *testing.T) {
+	stores := map[string]redblocks.Store{
+		"memory":  redblocks.NewMemoryStore(),
+		"redis":   redblocks.NewRedisStore(newPool()),
+		"goredis": redblocks.NewGoredisStore(redisdb.WithContext),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "TestStoreAddDependent:" + name
+			if err := store.Delete(ctx, redblocks.DependentsKey(key)); err != nil {
+				t.Error(err)
+			}
+
+			// Shorter than a second
+			if err := store.AddDependent(ctx, key, "a", time.Millisecond*500); err != nil {
+				t.Error(err)
+			}
+			if err := store.AddDependent(ctx, key, "b", time.Second*100); err != nil {
+				t.Error(err)
+			}
+
+			dependents, err := store.Dependents(ctx, key)
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(dependents, []string{"a", "b"}, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package redblocks
+
+import (
+	"context"
+)
+
+// IDWithScoreIterator streams members, so large sets do not need to be held in memory at once.
+// Next returns false when there are no more members.
+type IDWithScoreIterator interface {
+	Next(ctx context.Context) (IDWithScore, bool, error)
+}
+
+// StreamSet is a Set which can stream its members. Update uses Stream instead of Get when the set implements it.
+type StreamSet interface {
+	Set
+	Stream(ctx context.Context) (IDWithScoreIterator, error)
+}
+
+type sliceIterator struct {
+	idsWithScore []IDWithScore
+	i            int
+}
+
+func SliceIterator(idsWithScore []IDWithScore) IDWithScoreIterator {
+	return &sliceIterator{idsWithScore: idsWithScore}
+}
+
+func (it *sliceIterator) Next(ctx context.Context) (IDWithScore, bool, error) {
+	if it.i >= len(it.idsWithScore) {
+		return IDWithScore{}, false, nil
+	}
+	it.i++
+	return it.idsWithScore[it.i-1], true, nil
+}
+
+type chanIterator struct {
+	ch <-chan IDWithScore
+}
+
+// ChanIterator iterates members sent to ch until it is closed.
+func ChanIterator(ch <-chan IDWithScore) IDWithScoreIterator {
+	return chanIterator{ch: ch}
+}
+
+func (it chanIterator) Next(ctx context.Context) (IDWithScore, bool, error) {
+	select {
+	case <-ctx.Done():
+		return IDWithScore{}, false, ctx.Err()
+	case idWithScore, ok := <-it.ch:
+		return idWithScore, ok, nil
+	}
+}
+
+// nextChunk reads at most size members from it into buf
+func nextChunk(ctx context.Context, it IDWithScoreIterator, buf []IDWithScore, size int) ([]IDWithScore, error) {
+	buf = buf[:0]
+	for len(buf) < size {
+		idWithScore, ok, err := it.Next(ctx)
+		if err != nil {
+			return buf, err
+		}
+		if !ok {
+			break
+		}
+		buf = append(buf, idWithScore)
+	}
+	return buf, nil
+}
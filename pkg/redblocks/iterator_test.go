@@ -0,0 +1,69 @@
+package redblocks_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+type streamSetImp struct {
+	name string
+	n    int
+}
+
+func (s streamSetImp) KeySuffix() string {
+	return s.name
+}
+
+func (s streamSetImp) Get(ctx context.Context) ([]redblocks.IDWithScore, error) {
+	panic("Get must not be called on StreamSet")
+}
+
+func (s streamSetImp) Stream(ctx context.Context) (redblocks.IDWithScoreIterator, error) {
+	ch := make(chan redblocks.IDWithScore)
+	go func() {
+		defer close(ch)
+		for i := 0; i < s.n; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- redblocks.IDWithScore{ID: redblocks.ID(strconv.Itoa(i)), Score: float64(i)}:
+			}
+		}
+	}()
+	return redblocks.ChanIterator(ch), nil
+}
+
+func (s streamSetImp) CacheTime() time.Duration {
+	return time.Second * 100
+}
+
+func (s streamSetImp) NotAvailableTTL() time.Duration {
+	return time.Second * 10
+}
+
+func TestStreamSet(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	set := redblocks.Compose(streamSetImp{name: "stream", n: 2500}, store)
+	ctx := context.Background()
+
+	count, err := set.Count(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(count, int64(2500)); diff != "" {
+		t.Errorf(diff)
+	}
+
+	result, err := set.IDsWithScore(ctx, redblocks.WithPagenation(0, 1), redblocks.WithOrder(redblocks.Desc))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "2499", Score: 2499}, {ID: "2498", Score: 2498}}); diff != "" {
+		t.Errorf(diff)
+	}
+}
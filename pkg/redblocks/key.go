@@ -0,0 +1,126 @@
+package redblocks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Keys of composed sets longer than maxKeyLength are replaced by their hash
+const maxKeyLength = 200
+
+// Canonical is implemented by composed sets whose Key may be hashed.
+type Canonical interface {
+	CanonicalKey() string
+}
+
+// CanonicalKey returns the readable form of set.Key(), which is useful for debugging hashed keys.
+func CanonicalKey(set ComposedSet) string {
+	if c, ok := set.(Canonical); ok {
+		return c.CanonicalKey()
+	}
+	return set.Key()
+}
+
+// Prefixed is implemented by composed sets whose keys are namespaced by WithPrefix.
+type Prefixed interface {
+	KeyPrefix() string
+}
+
+// KeyPrefix returns the prefix of the key of set. Operators take the prefix shared by all of their children.
+func KeyPrefix(set interface{}) string {
+	if p, ok := set.(Prefixed); ok {
+		return p.KeyPrefix()
+	}
+	return ""
+}
+
+// prefixKey namespaces key like `prefix:key`. Every key of a composed set is made by it.
+func prefixKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + ":" + key
+}
+
+// sharedPrefix returns the prefix of sets, or empty when they have different prefixes.
+func sharedPrefix(sets []ComposedSet) string {
+	if len(sets) == 0 {
+		return ""
+	}
+	prefix := KeyPrefix(sets[0])
+	for _, set := range sets[1:] {
+		if KeyPrefix(set) != prefix {
+			return ""
+		}
+	}
+	return prefix
+}
+
+// canonicalKey encodes an operator, its parameters and child keys like `union[SUM;1,2](5:tokyo,5:osaka)`.
+// Child keys are length prefixed, so they can contain any characters without making the key ambiguous.
+func canonicalKey(op string, params []string, keys []string) string {
+	var b strings.Builder
+	b.WriteString(op)
+	if len(params) > 0 {
+		b.WriteString("[")
+		b.WriteString(strings.Join(params, ";"))
+		b.WriteString("]")
+	}
+	b.WriteString("(")
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(strconv.Itoa(len(key)))
+		b.WriteString(":")
+		b.WriteString(key)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// boundedKey hashes canonical when it is too long. The hashed key keeps prefix.
+func boundedKey(prefix string, op string, canonical string) string {
+	if len(canonical) <= maxKeyLength {
+		return canonical
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return prefixKey(prefix, op+"#"+hex.EncodeToString(sum[:]))
+}
+
+// weightsParam formats weights. Empty weights are the same as all 1, as Redis does.
+func weightsParam(weights []float64, n int) string {
+	ws := make([]string, n, n)
+	for i := range ws {
+		if len(weights) == 0 {
+			ws[i] = "1"
+			continue
+		}
+		if i < len(weights) {
+			ws[i] = strconv.FormatFloat(weights[i], 'g', -1, 64)
+		}
+	}
+	return strings.Join(ws, ",")
+}
+
+// effectiveWeights returns weights of n sets. Empty weights are the same as all 1.
+func effectiveWeights(weights []float64, n int) []float64 {
+	if len(weights) > 0 {
+		return weights
+	}
+	ws := make([]float64, n, n)
+	for i := range ws {
+		ws[i] = 1
+	}
+	return ws
+}
+
+func childKeys(sets []ComposedSet) []string {
+	keys := make([]string, len(sets), len(sets))
+	for i, set := range sets {
+		keys[i] = set.Key()
+	}
+	return keys
+}
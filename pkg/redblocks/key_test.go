@@ -0,0 +1,159 @@
+package redblocks_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func TestCompositeKey(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	tokyo := redblocks.Compose(NewScoredSet("tokyo", []redblocks.IDWithScore{{ID: "1", Score: 1}}), store)
+	osaka := redblocks.Compose(NewScoredSet("osaka", []redblocks.IDWithScore{{ID: "1", Score: 2}}), store)
+
+	sum := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, []float64{1, 1}, redblocks.Sum, tokyo, osaka)
+	max := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, []float64{1, 1}, redblocks.Max, tokyo, osaka)
+	weighted := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, []float64{1, 2}, redblocks.Sum, tokyo, osaka)
+	intersection := redblocks.NewIntersectionSet(store, time.Second*100, time.Second*10, []float64{1, 1}, redblocks.Sum, tokyo, osaka)
+
+	keys := map[string]bool{}
+	for _, set := range []redblocks.ComposedSet{sum, max, weighted, intersection} {
+		if keys[set.Key()] {
+			t.Errorf("duplicated key: %v", set.Key())
+		}
+		keys[set.Key()] = true
+	}
+
+	want := "union[SUM;1,1](" + strconv.Itoa(len(tokyo.Key())) + ":" + tokyo.Key() + "," + strconv.Itoa(len(osaka.Key())) + ":" + osaka.Key() + ")"
+	if diff := cmp.Diff(sum.Key(), want); diff != "" {
+		t.Errorf(diff)
+	}
+
+	ctx := context.Background()
+	sumResult, err := sum.IDsWithScore(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	maxResult, err := max.IDsWithScore(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(sumResult, []redblocks.IDWithScore{{ID: "1", Score: 3}}); diff != "" {
+		t.Errorf(diff)
+	}
+	if diff := cmp.Diff(maxResult, []redblocks.IDWithScore{{ID: "1", Score: 2}}); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestCompositeKeyAmbiguousSuffix(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	a := redblocks.Compose(NewScoredSet("a|b", nil), store)
+	b := redblocks.Compose(NewScoredSet("c", nil), store)
+	c := redblocks.Compose(NewScoredSet("a", nil), store)
+	d := redblocks.Compose(NewScoredSet("b|c", nil), store)
+
+	ab := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, []float64{1, 1}, redblocks.Sum, a, b)
+	cd := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, []float64{1, 1}, redblocks.Sum, c, d)
+	if ab.Key() == cd.Key() {
+		t.Errorf("keys must be different: %v", ab.Key())
+	}
+}
+
+func TestCompositeKeyHashed(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	sets := []redblocks.ComposedSet{}
+	for i := 0; i < 20; i++ {
+		sets = append(sets, redblocks.Compose(NewScoredSet(strings.Repeat("x", i+1), nil), store))
+	}
+	union := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, sets...)
+
+	if len(union.Key()) > 200 {
+		t.Errorf("key is too long: %v", len(union.Key()))
+	}
+	if !strings.HasPrefix(union.Key(), "union#") {
+		t.Errorf("key is not hashed: %v", union.Key())
+	}
+	if !strings.HasPrefix(redblocks.CanonicalKey(union), "union[SUM;1,1,1") {
+		t.Errorf("canonical key is not readable: %v", redblocks.CanonicalKey(union))
+	}
+}
+
+func TestComposeOptionKey(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	region := NewScoredSet("tokyo", []redblocks.IDWithScore{{ID: "1", Score: 1}})
+
+	tests := []struct {
+		opts []redblocks.ComposeOption
+		want string
+	}{
+		{
+			opts: nil,
+			want: "redblocks_test.scoredSetImp:tokyo",
+		},
+		{
+			opts: []redblocks.ComposeOption{redblocks.WithKey("region")},
+			want: "region:tokyo",
+		},
+		{
+			opts: []redblocks.ComposeOption{redblocks.WithKey("region"), redblocks.WithPrefix("service:prod"), redblocks.WithVersion("2")},
+			want: "service:prod:region@2:tokyo",
+		},
+	}
+
+	for _, test := range tests {
+		set := redblocks.Compose(region, store, test.opts...)
+		if diff := cmp.Diff(set.Key(), test.want); diff != "" {
+			t.Errorf(diff)
+		}
+	}
+
+	v1 := redblocks.Compose(region, store, redblocks.WithVersion("1"))
+	v2 := redblocks.Compose(region, store, redblocks.WithVersion("2"))
+	other := redblocks.Compose(NewScoredSet("osaka", nil), store)
+	union1 := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, v1, other)
+	union2 := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, v2, other)
+	if union1.Key() == union2.Key() {
+		t.Errorf("derived keys must be different: %v", union1.Key())
+	}
+}
+
+func TestComposeOptionPrefix(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	tokyo := redblocks.Compose(NewScoredSet("tokyo", nil), store, redblocks.WithPrefix("service"))
+	osaka := redblocks.Compose(NewScoredSet("osaka", nil), store, redblocks.WithPrefix("service"))
+	other := redblocks.Compose(NewScoredSet("nagoya", nil), store, redblocks.WithPrefix("other"))
+
+	union := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, tokyo, osaka)
+	nested := redblocks.NewSubtractionSet(store, time.Second*100, time.Second*10, union, tokyo)
+	mixed := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, tokyo, other)
+
+	for _, set := range []redblocks.ComposedSet{union, nested} {
+		if !strings.HasPrefix(set.Key(), "service:") {
+			t.Errorf("key is not prefixed: %v", set.Key())
+		}
+		if diff := cmp.Diff(redblocks.KeyPrefix(set), "service"); diff != "" {
+			t.Errorf(diff)
+		}
+	}
+	if !strings.HasPrefix(mixed.Key(), "union[") {
+		t.Errorf("key of children with different prefixes must not be prefixed: %v", mixed.Key())
+	}
+
+	sets := []redblocks.ComposedSet{}
+	for i := 0; i < 20; i++ {
+		sets = append(sets, redblocks.Compose(NewScoredSet(strings.Repeat("x", i+1), nil), store, redblocks.WithPrefix("service")))
+	}
+	hashed := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, sets...)
+	if !strings.HasPrefix(hashed.Key(), "service:union#") {
+		t.Errorf("hashed key is not prefixed: %v", hashed.Key())
+	}
+	if !strings.HasPrefix(redblocks.CanonicalKey(hashed), "service:union[") {
+		t.Errorf("canonical key is not prefixed: %v", redblocks.CanonicalKey(hashed))
+	}
+}
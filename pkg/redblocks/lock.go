@@ -0,0 +1,124 @@
+package redblocks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+type withLockImp struct {
+	WithUpdate
+	store Store
+	opt   LockOption
+}
+
+// ComposeLock makes Update run by only one caller at a time across processes.
+// Other callers wait for the result or serve the current data, depending on LockWaitPolicy.
+// Update does nothing when another caller refreshed the set while waiting for the lock.
+func ComposeLock(withUpdate WithUpdate, store Store, opts ...LockOption) WithUpdate {
+	opt, _ := LockOptionsToLockOption(opts)
+	return withLockImp{WithUpdate: withUpdate, store: store, opt: opt}
+}
+
+// ComposeWithLock is Compose with ComposeLock
+func ComposeWithLock(wrapped Set, store Store, lockOpts []LockOption, opts ...ComposeOption) ComposedSet {
+	return ComposeIDs(ComposeWarmup(ComposeLock(ComposeUpdate(wrapped, store, opts...), store, lockOpts...), store), store)
+}
+
+func LockKey(key string) string {
+	return key + ":lock"
+}
+
+func (c withLockImp) Update(ctx context.Context) error {
+	token, err := newToken()
+	if err != nil {
+		return fail.Wrap(err)
+	}
+
+	before, err := remaining(ctx, c.store, c.Key())
+	if err != nil {
+		return fail.Wrap(err)
+	}
+
+	deadline := time.Now().Add(c.opt.Timeout)
+	for {
+		locked, err := c.store.Lock(ctx, LockKey(c.Key()), token, c.opt.Timeout)
+		if err != nil {
+			return fail.Wrap(err)
+		}
+		if locked {
+			// Unlocked even if ctx is canceled, so others do not wait until Timeout
+			defer c.store.Unlock(context.WithoutCancel(ctx), LockKey(c.Key()), token)
+
+			// Previous holder may have refreshed it just before
+			after, err := remaining(ctx, c.store, c.Key())
+			if err != nil {
+				return fail.Wrap(err)
+			}
+			if after > before {
+				return nil
+			}
+			return fail.Wrap(c.WithUpdate.Update(ctx))
+		}
+
+		if c.opt.WaitPolicy == ServeStale {
+			exists, err := c.store.Exists(ctx, c.Key())
+			if err != nil {
+				return fail.Wrap(err)
+			}
+			if exists {
+				o, _ := observerOf(c.store)
+				o.StaleServe(ctx, c.Key())
+				return nil
+			}
+		}
+
+		available, err := c.Available(ctx)
+		if err != nil {
+			return fail.Wrap(err)
+		}
+		if available {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fail.Wrap(fail.New("Timed out waiting for lock"), fail.WithParam("key", LockKey(c.Key())))
+		}
+
+		select {
+		case <-ctx.Done():
+			return fail.Wrap(ctx.Err())
+		case <-time.After(c.opt.RetryInterval):
+		}
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fail.Wrap(err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (c withLockImp) Children() []ComposedSet {
+	return Children(c.WithUpdate)
+}
+
+func (c withLockImp) CanonicalKey() string {
+	if canonical, ok := c.WithUpdate.(Canonical); ok {
+		return canonical.CanonicalKey()
+	}
+	return c.Key()
+}
+
+func (c withLockImp) KeyPrefix() string {
+	return KeyPrefix(c.WithUpdate)
+}
+
+func (c withLockImp) Describe() Operation {
+	return Describe(c.WithUpdate)
+}
@@ -0,0 +1,149 @@
+package redblocks_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+type slowSetImp struct {
+	scoredSetImp
+	calls *int32
+	delay time.Duration
+}
+
+func (s slowSetImp) Get(ctx context.Context) ([]redblocks.IDWithScore, error) {
+	atomic.AddInt32(s.calls, 1)
+	time.Sleep(s.delay)
+	return s.scoredSetImp.Get(ctx)
+}
+
+func TestComposeWithLock(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	var calls int32
+	slow := slowSetImp{
+		scoredSetImp: scoredSetImp{name: "TestComposeWithLock", idsWithScore: []redblocks.IDWithScore{{ID: "1", Score: 1}}},
+		calls:        &calls,
+		delay:        time.Millisecond * 50,
+	}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			set := redblocks.ComposeWithLock(slow, store, []redblocks.LockOption{redblocks.WithLockRetryInterval(time.Millisecond)})
+			ids, err := set.IDs(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(ids, []redblocks.ID{"1"}); diff != "" {
+				t.Errorf(diff)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if diff := cmp.Diff(atomic.LoadInt32(&calls), int32(1)); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestComposeWithLockCanceled(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	var calls int32
+	slow := slowSetImp{
+		scoredSetImp: scoredSetImp{name: "TestComposeWithLockCanceled", idsWithScore: []redblocks.IDWithScore{{ID: "1", Score: 1}}},
+		calls:        &calls,
+		delay:        time.Millisecond * 50,
+	}
+	set := redblocks.ComposeWithLock(slow, store, []redblocks.LockOption{redblocks.WithLockTimeout(time.Second * 100)})
+
+	// Canceled while the holder is refreshing
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := set.Update(ctx); err == nil {
+		t.Error("Expected not nil")
+	}
+
+	locked, err := store.Lock(context.Background(), redblocks.LockKey(set.Key()), "other", time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(locked, true); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestComposeWithLockServeStale(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	var calls int32
+	slow := slowSetImp{
+		scoredSetImp: scoredSetImp{name: "TestComposeWithLockServeStale", idsWithScore: []redblocks.IDWithScore{{ID: "1", Score: 1}}},
+		calls:        &calls,
+	}
+	set := redblocks.ComposeWithLock(slow, store, []redblocks.LockOption{redblocks.WithLockWaitPolicy(redblocks.ServeStale), redblocks.WithLockTimeout(time.Millisecond * 100)})
+	ctx := context.Background()
+
+	// Stale data which is below NotAvailableTTL
+	err := store.Save(ctx, set.Key(), []redblocks.IDWithScore{{ID: "stale"}}, time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	locked, err := store.Lock(ctx, redblocks.LockKey(set.Key()), "other", time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	if !locked {
+		t.Errorf("Expected locked")
+	}
+
+	if err := set.Warmup(ctx); err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(atomic.LoadInt32(&calls), int32(0)); diff != "" {
+		t.Errorf(diff)
+	}
+
+	waiting := redblocks.ComposeWithLock(slow, store, []redblocks.LockOption{redblocks.WithLockTimeout(time.Millisecond * 100), redblocks.WithLockRetryInterval(time.Millisecond * 10)})
+	if err := waiting.Warmup(ctx); err == nil {
+		t.Errorf("Expected not nil")
+	}
+
+	if err := store.Unlock(ctx, redblocks.LockKey(set.Key()), "other"); err != nil {
+		t.Error(err)
+	}
+	if err := set.Warmup(ctx); err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(atomic.LoadInt32(&calls), int32(1)); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestComposeWithLockPrefix(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	region := NewScoredSet("tokyo", []redblocks.IDWithScore{{ID: "1", Score: 1}})
+	set := redblocks.ComposeWithLock(region, store, []redblocks.LockOption{redblocks.WithLockTimeout(time.Millisecond * 50), redblocks.WithLockRetryInterval(time.Millisecond)}, redblocks.WithKey("region"), redblocks.WithPrefix("service"))
+	if diff := cmp.Diff(set.Key(), "service:region:tokyo"); diff != "" {
+		t.Errorf(diff)
+	}
+
+	ctx := context.Background()
+	locked, err := store.Lock(ctx, "service:region:tokyo:lock", "other", time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	if !locked {
+		t.Errorf("Expected locked")
+	}
+	if err := set.Update(ctx); err == nil {
+		t.Errorf("Expected not nil")
+	}
+}
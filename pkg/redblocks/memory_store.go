@@ -0,0 +1,685 @@
+package redblocks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+type MemoryStoreOption struct {
+	Now func() time.Time
+}
+
+func MemoryStoreOptionsToMemoryStoreOption(opts []MemoryStoreOption) (MemoryStoreOption, error) {
+	opt := MemoryStoreOption{
+		Now: time.Now,
+	}
+	for _, o := range opts {
+		if o.Now != nil {
+			opt.Now = o.Now
+		}
+	}
+
+	return opt, nil
+}
+
+// WithClock replaces the clock used to compute expiry. Useful for tests which need to move time forward.
+func WithClock(now func() time.Time) MemoryStoreOption {
+	return MemoryStoreOption{
+		Now: now,
+	}
+}
+
+// NewMemoryStore returns a Store which keeps sorted sets in process memory.
+// It behaves like the Redis backed stores (ordering, weights, aggregate and expiry), so it can be used in tests and local development.
+func NewMemoryStore(opts ...MemoryStoreOption) Store {
+	opt, _ := MemoryStoreOptionsToMemoryStoreOption(opts)
+	return &memoryStoreImp{
+		now:        opt.Now,
+		sets:       map[string]*memorySortedSet{},
+		locks:      map[string]memoryLock{},
+		watermarks: map[string]memoryWatermark{},
+		dependents: map[string]map[string]time.Time{},
+	}
+}
+
+type memoryStoreImp struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	sets       map[string]*memorySortedSet
+	locks      map[string]memoryLock
+	watermarks map[string]memoryWatermark      // Kept apart from sets like WatermarkKey, so replacing or removing a set keeps its watermark
+	dependents map[string]map[string]time.Time // Expire of each dependent
+}
+
+type memoryLock struct {
+	token    string
+	expireAt time.Time
+}
+
+type memoryWatermark struct {
+	watermark string
+	expireAt  time.Time
+}
+
+type memorySortedSet struct {
+	scores   map[ID]float64
+	expireAt time.Time // Zero value means no expiry
+}
+
+// Save replaces key with idsWithScore, same as the Redis backed stores.
+func (s *memoryStoreImp) Save(ctx context.Context, key string, idsWithScore []IDWithScore, expire time.Duration) error {
+	return fail.Wrap(s.SaveIterator(ctx, key, SliceIterator(idsWithScore), expire))
+}
+
+func (s *memoryStoreImp) SaveIterator(ctx context.Context, key string, it IDWithScoreIterator, expire time.Duration) error {
+	scores := map[ID]float64{}
+	for {
+		idWithScore, ok, err := it.Next(ctx)
+		if err != nil {
+			return fail.Wrap(canceled(ctx, err))
+		}
+		if !ok {
+			break
+		}
+		scores[idWithScore.ID] = idWithScore.Score
+	}
+
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	s.store(key, scores, expire)
+	return nil
+}
+
+func (s *memoryStoreImp) GetIDs(ctx context.Context, key string, head int64, tail int64, order Order) ([]ID, error) {
+	idsWithScore, err := s.GetIDsWithScore(ctx, key, head, tail, order)
+	if err != nil {
+		return []ID{}, fail.Wrap(err)
+	}
+
+	ids := make([]ID, len(idsWithScore), len(idsWithScore))
+	for i, idWithScore := range idsWithScore {
+		ids[i] = idWithScore.ID
+	}
+	return ids, nil
+}
+
+func (s *memoryStoreImp) GetIDsWithScore(ctx context.Context, key string, head int64, tail int64, order Order) ([]IDWithScore, error) {
+	if order != Asc && order != Desc {
+		return []IDWithScore{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+
+	if err := s.lock(ctx); err != nil {
+		return []IDWithScore{}, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	set := s.get(key)
+	if set == nil {
+		return []IDWithScore{}, nil
+	}
+
+	sorted := set.sorted(order)
+	start, stop := rangeIndex(int64(len(sorted)), head, tail)
+	if start > stop {
+		return []IDWithScore{}, nil
+	}
+
+	result := make([]IDWithScore, stop-start+1, stop-start+1)
+	copy(result, sorted[start:stop+1])
+	return result, nil
+}
+
+func (s *memoryStoreImp) GetIDsByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]ID, error) {
+	idsWithScore, err := s.GetIDsWithScoreByScore(ctx, key, min, max, offset, count, order)
+	if err != nil {
+		return []ID{}, fail.Wrap(err)
+	}
+
+	ids := make([]ID, len(idsWithScore), len(idsWithScore))
+	for i, idWithScore := range idsWithScore {
+		ids[i] = idWithScore.ID
+	}
+	return ids, nil
+}
+
+func (s *memoryStoreImp) GetIDsWithScoreByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]IDWithScore, error) {
+	if order != Asc && order != Desc {
+		return []IDWithScore{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+
+	if err := s.lock(ctx); err != nil {
+		return []IDWithScore{}, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	set := s.get(key)
+	if set == nil {
+		return []IDWithScore{}, nil
+	}
+
+	result := []IDWithScore{}
+	for _, idWithScore := range set.sorted(order) {
+		if !min.AboveMin(idWithScore.Score) || !max.BelowMax(idWithScore.Score) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if count >= 0 && int64(len(result)) >= count {
+			break
+		}
+		result = append(result, idWithScore)
+	}
+	return result, nil
+}
+
+func (s *memoryStoreImp) Exists(ctx context.Context, key string) (bool, error) {
+	if err := s.lock(ctx); err != nil {
+		return false, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	return s.get(key) != nil, nil
+}
+
+func (s *memoryStoreImp) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if err := s.lock(ctx); err != nil {
+		return 0, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	set := s.get(key)
+	if set == nil {
+		return 0, fail.Wrap(ErrKeyNotFound, fail.WithParam("key", key))
+	}
+	if set.expireAt.IsZero() {
+		return 0, fail.Wrap(ErrNoExpiry, fail.WithParam("key", key))
+	}
+
+	// Same resolution as https://redis.io/commands/TTL
+	return set.expireAt.Sub(s.now()).Truncate(time.Second), nil
+}
+
+func (s *memoryStoreImp) Interstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error {
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	weights, err := normalizeWeights(weights, len(keys))
+	if err != nil {
+		return fail.Wrap(err)
+	}
+
+	result := map[ID]float64{}
+	for i, key := range keys {
+		set := s.get(key)
+		if set == nil {
+			result = map[ID]float64{}
+			break
+		}
+
+		if i == 0 {
+			for id, score := range set.scores {
+				result[id] = score * weights[i]
+			}
+			continue
+		}
+
+		for id, score := range result {
+			other, ok := set.scores[id]
+			if !ok {
+				delete(result, id)
+				continue
+			}
+			result[id] = aggregateScore(aggregate, score, other*weights[i])
+		}
+	}
+
+	s.store(dst, result, expire)
+	return nil
+}
+
+func (s *memoryStoreImp) Unionstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error {
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	weights, err := normalizeWeights(weights, len(keys))
+	if err != nil {
+		return fail.Wrap(err)
+	}
+
+	result := map[ID]float64{}
+	for i, key := range keys {
+		set := s.get(key)
+		if set == nil {
+			continue
+		}
+
+		for id, score := range set.scores {
+			weighted := score * weights[i]
+			if current, ok := result[id]; ok {
+				result[id] = aggregateScore(aggregate, current, weighted)
+			} else {
+				result[id] = weighted
+			}
+		}
+	}
+
+	s.store(dst, result, expire)
+	return nil
+}
+
+func (s *memoryStoreImp) Subtraction(ctx context.Context, dst string, expire time.Duration, key string, subtrahends ...string) error {
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	result := map[ID]float64{}
+	if set := s.get(key); set != nil {
+		for id, score := range set.scores {
+			result[id] = score
+		}
+	}
+	for _, k := range subtrahends {
+		set := s.get(k)
+		if set == nil {
+			continue
+		}
+		for id := range set.scores {
+			delete(result, id)
+		}
+	}
+
+	s.store(dst, result, expire)
+	return nil
+}
+
+func (s *memoryStoreImp) TopN(ctx context.Context, dst string, expire time.Duration, n int64, order Order, key string) error {
+	if _, _, err := topNRange(n, order); err != nil {
+		return fail.Wrap(err)
+	}
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	result := map[ID]float64{}
+	if set := s.get(key); set != nil {
+		for i, idWithScore := range set.sorted(order) {
+			if int64(i) >= n {
+				break
+			}
+			result[idWithScore.ID] = idWithScore.Score
+		}
+	}
+
+	s.store(dst, result, expire)
+	return nil
+}
+
+func (s *memoryStoreImp) FilterByScore(ctx context.Context, dst string, expire time.Duration, min ScoreBound, max ScoreBound, key string) error {
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	result := map[ID]float64{}
+	if set := s.get(key); set != nil {
+		for id, score := range set.scores {
+			if min.AboveMin(score) && max.BelowMax(score) {
+				result[id] = score
+			}
+		}
+	}
+
+	s.store(dst, result, expire)
+	return nil
+}
+
+func (s *memoryStoreImp) ApplyDelta(ctx context.Context, key string, from string, delta Delta, expire time.Duration) (bool, error) {
+	if err := validateDelta(delta, expire); err != nil {
+		return false, fail.Wrap(err)
+	}
+
+	if err := s.lock(ctx); err != nil {
+		return false, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	// A delta on a missing key would leave a set of only the delta
+	set := s.get(key)
+	if set == nil || s.watermark(key) != from {
+		return false, nil
+	}
+
+	scores := set.scores
+	for _, id := range delta.Removed {
+		delete(scores, id)
+	}
+	for _, v := range delta.Added {
+		scores[v.ID] = v.Score
+	}
+	for _, v := range delta.Incremented {
+		scores[v.ID] += v.Score
+	}
+
+	s.store(key, scores, expire)
+	s.setWatermark(key, delta.Watermark, expire)
+	return true, nil
+}
+
+func (s *memoryStoreImp) Watermark(ctx context.Context, key string) (string, bool, error) {
+	if err := s.lock(ctx); err != nil {
+		return "", false, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	watermark := s.watermark(key)
+	return watermark, watermark != "", nil
+}
+
+func (s *memoryStoreImp) SetWatermark(ctx context.Context, key string, watermark string, expire time.Duration) error {
+	if watermark != "" {
+		if err := validateWatermarkExpire(expire); err != nil {
+			return fail.Wrap(err)
+		}
+	}
+
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	s.setWatermark(key, watermark, expire)
+	return nil
+}
+
+func (s *memoryStoreImp) Delete(ctx context.Context, keys ...string) error {
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.sets, key)
+	}
+	return nil
+}
+
+func (s *memoryStoreImp) AddDependent(ctx context.Context, key string, dependent string, expire time.Duration) error {
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	if s.dependents[key] == nil {
+		s.dependents[key] = map[string]time.Time{}
+	}
+	if expireAt := s.now().Add(expire); expireAt.After(s.dependents[key][dependent]) {
+		s.dependents[key][dependent] = expireAt
+	}
+	return nil
+}
+
+func (s *memoryStoreImp) Dependents(ctx context.Context, key string) ([]string, error) {
+	if err := s.lock(ctx); err != nil {
+		return []string{}, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	dependents := []string{}
+	for dependent, expireAt := range s.dependents[key] {
+		if !s.now().Before(expireAt) {
+			delete(s.dependents[key], dependent)
+			continue
+		}
+		dependents = append(dependents, dependent)
+	}
+	sort.Strings(dependents)
+	return dependents, nil
+}
+
+func (s *memoryStoreImp) Count(ctx context.Context, key string) (int64, error) {
+	if err := s.lock(ctx); err != nil {
+		return 0, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	set := s.get(key)
+	if set == nil {
+		return 0, nil
+	}
+	return int64(len(set.scores)), nil
+}
+
+func (s *memoryStoreImp) CountByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound) (int64, error) {
+	if err := s.lock(ctx); err != nil {
+		return 0, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	set := s.get(key)
+	if set == nil {
+		return 0, nil
+	}
+
+	var count int64
+	for _, score := range set.scores {
+		if min.AboveMin(score) && max.BelowMax(score) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryStoreImp) Scores(ctx context.Context, key string, ids ...ID) (map[ID]float64, error) {
+	if err := s.lock(ctx); err != nil {
+		return map[ID]float64{}, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	scores := map[ID]float64{}
+	set := s.get(key)
+	if set == nil {
+		return scores, nil
+	}
+	for _, id := range ids {
+		if score, ok := set.scores[id]; ok {
+			scores[id] = score
+		}
+	}
+	return scores, nil
+}
+
+func (s *memoryStoreImp) Rank(ctx context.Context, key string, id ID, order Order) (int64, bool, error) {
+	if order != Asc && order != Desc {
+		return 0, false, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+
+	if err := s.lock(ctx); err != nil {
+		return 0, false, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	set := s.get(key)
+	if set == nil {
+		return 0, false, nil
+	}
+	if _, ok := set.scores[id]; !ok {
+		return 0, false, nil
+	}
+	for i, idWithScore := range set.sorted(order) {
+		if idWithScore.ID == id {
+			return int64(i), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (s *memoryStoreImp) Lock(ctx context.Context, key string, token string, timeout time.Duration) (bool, error) {
+	if err := s.lock(ctx); err != nil {
+		return false, fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	if lock, ok := s.locks[key]; ok && s.now().Before(lock.expireAt) {
+		return false, nil
+	}
+	s.locks[key] = memoryLock{token: token, expireAt: s.now().Add(timeout)}
+	return true, nil
+}
+
+func (s *memoryStoreImp) Unlock(ctx context.Context, key string, token string) error {
+	if err := s.lock(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+	defer s.mu.Unlock()
+
+	if lock, ok := s.locks[key]; ok && lock.token == token {
+		delete(s.locks, key)
+	}
+	return nil
+}
+
+// lock locks mu unless ctx is already done. Callers must unlock mu when it returns nil.
+func (s *memoryStoreImp) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return canceled(ctx, err)
+	}
+	s.mu.Lock()
+	return nil
+}
+
+// get returns nil when the key does not exist or is expired. Callers must hold mu.
+func (s *memoryStoreImp) get(key string) *memorySortedSet {
+	set, ok := s.sets[key]
+	if !ok {
+		return nil
+	}
+	if !set.expireAt.IsZero() && !s.now().Before(set.expireAt) {
+		delete(s.sets, key)
+		return nil
+	}
+	return set
+}
+
+// store replaces dst like Z*STORE does. Redis does not keep empty sorted sets, so an empty result deletes dst.
+func (s *memoryStoreImp) store(dst string, scores map[ID]float64, expire time.Duration) {
+	if len(scores) == 0 {
+		delete(s.sets, dst)
+		return
+	}
+	s.sets[dst] = &memorySortedSet{scores: scores}
+	s.expire(dst, expire)
+}
+
+// watermark returns an empty string when the watermark of key does not exist or is expired. Callers must hold mu.
+func (s *memoryStoreImp) watermark(key string) string {
+	watermark, ok := s.watermarks[key]
+	if !ok {
+		return ""
+	}
+	if !s.now().Before(watermark.expireAt) {
+		delete(s.watermarks, key)
+		return ""
+	}
+	return watermark.watermark
+}
+
+// setWatermark removes the watermark of key when watermark is empty. Callers must hold mu.
+func (s *memoryStoreImp) setWatermark(key string, watermark string, expire time.Duration) {
+	if watermark == "" {
+		delete(s.watermarks, key)
+		return
+	}
+	s.watermarks[key] = memoryWatermark{watermark: watermark, expireAt: s.now().Add(expire)}
+}
+
+func (s *memoryStoreImp) expire(key string, expire time.Duration) {
+	// Same as EXPIRE with a non positive timeout
+	if expire <= 0 {
+		delete(s.sets, key)
+		return
+	}
+	s.sets[key].expireAt = s.now().Add(expire)
+}
+
+func (set *memorySortedSet) sorted(order Order) []IDWithScore {
+	idsWithScore := make([]IDWithScore, 0, len(set.scores))
+	for id, score := range set.scores {
+		idsWithScore = append(idsWithScore, IDWithScore{ID: id, Score: score})
+	}
+
+	// Members with the same score are ordered lexicographically, same as Redis.
+	sort.Slice(idsWithScore, func(i, j int) bool {
+		a, b := idsWithScore[i], idsWithScore[j]
+		if order == Desc {
+			a, b = b, a
+		}
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		return a.ID < b.ID
+	})
+	return idsWithScore
+}
+
+// rangeIndex resolves ZRANGE style indexes, which can be negative, into [start, stop].
+func rangeIndex(length int64, head int64, tail int64) (int64, int64) {
+	if head < 0 {
+		head += length
+	}
+	if tail < 0 {
+		tail += length
+	}
+	if head < 0 {
+		head = 0
+	}
+	if tail >= length {
+		tail = length - 1
+	}
+	return head, tail
+}
+
+func normalizeWeights(weights []float64, n int) ([]float64, error) {
+	if len(weights) == 0 {
+		weights = make([]float64, n, n)
+		for i := range weights {
+			weights[i] = 1
+		}
+		return weights, nil
+	}
+	if len(weights) != n {
+		return nil, fail.Wrap(fail.New("Number of weights and keys must be the same"), fail.WithParam("weights", len(weights)), fail.WithParam("keys", n))
+	}
+	return weights, nil
+}
+
+func aggregateScore(aggregate Aggregate, a float64, b float64) float64 {
+	switch aggregate {
+	case Min:
+		if b < a {
+			return b
+		}
+		return a
+	case Max:
+		if b > a {
+			return b
+		}
+		return a
+	default:
+		return a + b
+	}
+}
@@ -0,0 +1,549 @@
+package redblocks_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func NewScoredSet(name string, idsWithScore []redblocks.IDWithScore) redblocks.Set {
+	return scoredSetImp{name: name, idsWithScore: idsWithScore}
+}
+
+type scoredSetImp struct {
+	name         string
+	idsWithScore []redblocks.IDWithScore
+}
+
+func (s scoredSetImp) KeySuffix() string {
+	return s.name
+}
+
+func (s scoredSetImp) Get(ctx context.Context) ([]redblocks.IDWithScore, error) {
+	return s.idsWithScore, nil
+}
+
+func (s scoredSetImp) CacheTime() time.Duration {
+	return time.Second * 100
+}
+
+func (s scoredSetImp) NotAvailableTTL() time.Duration {
+	return time.Second * 10
+}
+
+func TestMemoryStoreGetIDs(t *testing.T) {
+	memoryStore := redblocks.NewMemoryStore()
+	key := "TestMemoryStoreGetIDs"
+	ctx := context.Background()
+	err := memoryStore.Save(ctx, key, []redblocks.IDWithScore{
+		{
+			ID:    "2",
+			Score: 2,
+		},
+		{
+			ID:    "1",
+			Score: 1,
+		},
+		{
+			ID:    "0",
+			Score: 2,
+		},
+	}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ids, err := memoryStore.GetIDs(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"1", "0", "2"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	ids, err = memoryStore.GetIDs(ctx, key, 0, 1, redblocks.Desc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"2", "0"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	ids, err = memoryStore.GetIDs(ctx, key, -2, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"0", "2"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	ids, err = memoryStore.GetIDs(ctx, key, 5, 10, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{}); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	memoryStore := redblocks.NewMemoryStore(redblocks.WithClock(clock.Now))
+	key := "TestMemoryStoreTTL"
+	ctx := context.Background()
+
+	err := memoryStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "1"}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	clock.Add(30 * time.Second)
+	ttl, err := memoryStore.TTL(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ttl, 70*time.Second); diff != "" {
+		t.Errorf(diff)
+	}
+
+	clock.Add(70 * time.Second)
+	exists, err := memoryStore.Exists(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(exists, false); diff != "" {
+		t.Errorf(diff)
+	}
+
+	_, err = memoryStore.TTL(ctx, key)
+	if diff := cmp.Diff(err.Error(), "Not found"); diff != "" {
+		t.Errorf(diff)
+	}
+	if !errors.Is(err, redblocks.ErrKeyNotFound) {
+		t.Errorf("want ErrKeyNotFound but got %v", err)
+	}
+
+	_, err = memoryStore.GetIDs(ctx, key, 0, -1, redblocks.Order(100))
+	if !errors.Is(err, redblocks.ErrInvalidOrder) {
+		t.Errorf("want ErrInvalidOrder but got %v", err)
+	}
+}
+
+func TestMemoryStoreInterstore(t *testing.T) {
+	memoryStore := redblocks.NewMemoryStore()
+	key := "TestMemoryStoreInterstore"
+	key1 := "TestMemoryStoreInterstore1"
+	key2 := "TestMemoryStoreInterstore2"
+	ctx := context.Background()
+
+	err := memoryStore.Save(ctx, key1, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = memoryStore.Save(ctx, key2, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 5}, {ID: "3", Score: 3}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		aggregate redblocks.Aggregate
+		weights   []float64
+		want      []redblocks.IDWithScore
+	}{
+		{
+			aggregate: redblocks.Sum,
+			weights:   []float64{1, 2},
+			want:      []redblocks.IDWithScore{{ID: "1", Score: 3}, {ID: "2", Score: 12}},
+		},
+		{
+			aggregate: redblocks.Min,
+			weights:   []float64{1, 1},
+			want:      []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}},
+		},
+		{
+			aggregate: redblocks.Max,
+			weights:   []float64{1, 1},
+			want:      []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 5}},
+		},
+	}
+
+	for _, test := range tests {
+		err = memoryStore.Interstore(ctx, key, time.Second, test.weights, test.aggregate, key1, key2)
+		if err != nil {
+			t.Error(err)
+		}
+		result, err := memoryStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff(result, test.want); diff != "" {
+			t.Errorf("%v: %v", test.aggregate, diff)
+		}
+	}
+}
+
+func TestMemoryStoreUnionstore(t *testing.T) {
+	memoryStore := redblocks.NewMemoryStore()
+	key := "TestMemoryStoreUnionstore"
+	key1 := "TestMemoryStoreUnionstore1"
+	key2 := "TestMemoryStoreUnionstore2"
+	ctx := context.Background()
+
+	err := memoryStore.Save(ctx, key1, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = memoryStore.Save(ctx, key2, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}, {ID: "3", Score: 3}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = memoryStore.Unionstore(ctx, key, time.Second, []float64{1, 1}, redblocks.Sum, key1, key2)
+	if err != nil {
+		t.Error(err)
+	}
+	result, err := memoryStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "1", Score: 2}, {ID: "3", Score: 3}, {ID: "2", Score: 4}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	count, err := memoryStore.Count(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(count, int64(3)); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestMemoryStoreSubtraction(t *testing.T) {
+	memoryStore := redblocks.NewMemoryStore()
+	key := "TestMemoryStoreSubtraction"
+	key1 := "TestMemoryStoreSubtraction1"
+	key2 := "TestMemoryStoreSubtraction2"
+	ctx := context.Background()
+
+	err := memoryStore.Save(ctx, key1, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}, {ID: "4", Score: 3}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = memoryStore.Save(ctx, key2, []redblocks.IDWithScore{{ID: "1", Score: -100}, {ID: "2", Score: -100}, {ID: "3", Score: -100}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = memoryStore.Subtraction(ctx, key, time.Second*100, key1, key2)
+	if err != nil {
+		t.Error(err)
+	}
+	result, err := memoryStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "4", Score: 3}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	key3 := "TestMemoryStoreSubtraction3"
+	err = memoryStore.Save(ctx, key3, []redblocks.IDWithScore{{ID: "4", Score: 100}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = memoryStore.Subtraction(ctx, key, time.Second*100, key1, key2, key3)
+	if err != nil {
+		t.Error(err)
+	}
+	result, err = memoryStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{}); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestMemoryStoreCompose(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	store := redblocks.NewMemoryStore(redblocks.WithClock(clock.Now))
+	tokyo := redblocks.Compose(NewRegionSet("tokyo"), store)
+	osaka := redblocks.Compose(NewRegionSet("osaka"), store)
+	donotshow := redblocks.Compose(NewRegionSet("donotshow"), store)
+
+	ctx := context.Background()
+	union := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, []float64{1, 1}, redblocks.Sum, tokyo, osaka)
+	intersection := redblocks.NewIntersectionSet(store, time.Second*100, time.Second*10, []float64{1, 1}, redblocks.Sum, tokyo, union)
+	subtracted := redblocks.NewSubtractionSet(store, time.Second*100, time.Second*10, intersection, donotshow)
+
+	ids, err := union.IDs(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"test1", "test2", "test3", "test4"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	result, err := subtracted.IDsWithScore(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "test3", Score: 0}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	clock.Add(time.Second * 95)
+	available, err := subtracted.Available(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(available, false); diff != "" {
+		t.Errorf(diff)
+	}
+
+	if err := subtracted.Warmup(ctx); err != nil {
+		t.Error(err)
+	}
+	ttl, err := store.TTL(ctx, subtracted.Key())
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ttl, time.Second*100); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestMemoryStoreIDsByScore(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	osaka := redblocks.Compose(NewScoredSet("osaka", []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}, {ID: "3", Score: 3}, {ID: "4", Score: 4}}), store)
+	ctx := context.Background()
+
+	ids, err := osaka.IDs(ctx, redblocks.WithScoreRange(redblocks.Inclusive(2), redblocks.PositiveInf))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"2", "3", "4"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	result, err := osaka.IDsWithScore(ctx, redblocks.WithScoreRange(redblocks.Exclusive(1), redblocks.Exclusive(4)), redblocks.WithOrder(redblocks.Desc), redblocks.WithLimit(1, 5))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "2", Score: 2}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	ids, err = osaka.IDs(ctx, redblocks.WithScoreRange(redblocks.NegativeInf, redblocks.PositiveInf), redblocks.WithLimit(0, 0))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	count, err := osaka.CountByScore(ctx, redblocks.NegativeInf, redblocks.Inclusive(3))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(count, int64(3)); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestMemoryStoreSaveReplaces(t *testing.T) {
+	memoryStore := redblocks.NewMemoryStore()
+	key := "TestMemoryStoreSaveReplaces"
+	ctx := context.Background()
+
+	err := memoryStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = memoryStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "2", Score: 2}, {ID: "3", Score: 3}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ids, err := memoryStore.GetIDs(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"2", "3"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	err = memoryStore.Save(ctx, key, []redblocks.IDWithScore{}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	exists, err := memoryStore.Exists(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(exists, false); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestMemoryStoreCanceled(t *testing.T) {
+	memoryStore := redblocks.NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := memoryStore.GetIDs(ctx, "TestMemoryStoreCanceled", 0, -1, redblocks.Asc)
+	if !redblocks.IsCanceled(err) {
+		t.Errorf("want CanceledError but got %v", err)
+	}
+
+	set := redblocks.Compose(NewScoredSet("TestMemoryStoreCanceled", []redblocks.IDWithScore{{ID: "1", Score: 1}}), memoryStore)
+	err = set.Update(ctx)
+	if !redblocks.IsCanceled(err) {
+		t.Errorf("want CanceledError but got %v", err)
+	}
+	if redblocks.IsCanceled(errors.New("failed")) {
+		t.Errorf("want false")
+	}
+}
+
+func TestMemoryStoreScoresAndRank(t *testing.T) {
+	memoryStore := redblocks.NewMemoryStore()
+	key := "TestMemoryStoreScoresAndRank"
+	ctx := context.Background()
+
+	err := memoryStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}, {ID: "c", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	scores, err := memoryStore.Scores(ctx, key, "a", "c", "x")
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(scores, map[redblocks.ID]float64{"a": 1, "c": 2}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	tests := []struct {
+		id    redblocks.ID
+		order redblocks.Order
+		rank  int64
+		ok    bool
+	}{
+		{id: "a", order: redblocks.Asc, rank: 0, ok: true},
+		{id: "c", order: redblocks.Asc, rank: 2, ok: true},
+		{id: "c", order: redblocks.Desc, rank: 0, ok: true},
+		{id: "a", order: redblocks.Desc, rank: 2, ok: true},
+		{id: "x", order: redblocks.Asc, rank: 0, ok: false},
+	}
+	for _, test := range tests {
+		rank, ok, err := memoryStore.Rank(ctx, key, test.id, test.order)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff([]interface{}{rank, ok}, []interface{}{test.rank, test.ok}); diff != "" {
+			t.Errorf("%v %v: %v", test.id, test.order, diff)
+		}
+	}
+}
+
+func TestMemoryStoreComposedLookup(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	eligible := redblocks.Compose(NewScoredSet("eligible", []redblocks.IDWithScore{{ID: "1", Score: 10}, {ID: "2", Score: 20}, {ID: "3", Score: 30}}), store)
+	ctx := context.Background()
+
+	contains, err := eligible.Contains(ctx, "2", "4")
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(contains, map[redblocks.ID]bool{"2": true, "4": false}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	scores, err := eligible.Score(ctx, "1", "3")
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(scores, map[redblocks.ID]float64{"1": 10, "3": 30}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	rank, ok, err := eligible.Rank(ctx, "1", redblocks.Desc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff([]interface{}{rank, ok}, []interface{}{int64(2), true}); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestMemoryStoreChildFailure(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	ctx := context.Background()
+	tokyo := redblocks.Compose(NewScoredSet("tokyo", []redblocks.IDWithScore{{ID: "1", Score: 1}}), store)
+	broken := redblocks.Compose(failingSetImp{scoredSetImp{name: "broken"}}, store)
+
+	intersection := redblocks.NewIntersectionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, tokyo, broken)
+	_, err := intersection.IDs(ctx)
+	var warmupErr *redblocks.WarmupError
+	if !errors.As(err, &warmupErr) {
+		t.Fatalf("expected WarmupError, got %v", err)
+	}
+	if diff := cmp.Diff(len(warmupErr.Errs), 1); diff != "" {
+		t.Errorf(diff)
+	}
+	if _, ok := warmupErr.Errs[broken.Key()]; !ok {
+		t.Errorf("expected error of %s, got %v", broken.Key(), warmupErr)
+	}
+
+	var skipped []string
+	union := redblocks.NewUnionSetWithOptions(store, time.Second*100, time.Second*10, nil, redblocks.Sum, []redblocks.OperatorOption{
+		redblocks.WithChildFailurePolicy(redblocks.SkipFailedChildren),
+		redblocks.WithChildConcurrency(1),
+		redblocks.WithOnChildError(func(key string, err error) { skipped = append(skipped, key) }),
+	}, tokyo, broken)
+	ids, err := union.IDs(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"1"}); diff != "" {
+		t.Errorf(diff)
+	}
+	if diff := cmp.Diff(skipped, []string{broken.Key()}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	allBroken := redblocks.NewUnionSetWithOptions(store, time.Second*100, time.Second*10, nil, redblocks.Sum, []redblocks.OperatorOption{
+		redblocks.WithChildFailurePolicy(redblocks.SkipFailedChildren),
+	}, broken)
+	if _, err := allBroken.IDs(ctx); !errors.As(err, &warmupErr) {
+		t.Errorf("expected WarmupError, got %v", err)
+	}
+
+	skipping := []redblocks.OperatorOption{redblocks.WithChildFailurePolicy(redblocks.SkipFailedChildren)}
+	for _, set := range []redblocks.ComposedSet{
+		redblocks.NewIntersectionSetWithOptions(store, time.Second*100, time.Second*10, nil, redblocks.Sum, skipping, tokyo, broken),
+		redblocks.NewSubtractionSetWithOptions(store, time.Second*100, time.Second*10, tokyo, skipping, broken),
+	} {
+		if _, err := set.IDs(ctx); !errors.Is(err, redblocks.ErrUnsupportedFailurePolicy) {
+			t.Errorf("expected ErrUnsupportedFailurePolicy, got %v", err)
+		}
+	}
+}
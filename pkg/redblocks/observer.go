@@ -0,0 +1,93 @@
+package redblocks
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Observer is notified of cache and store events. It is attached to sets by NewObservedStore.
+// Methods are called synchronously, so they must be fast and safe for concurrent use.
+type Observer interface {
+	// CacheHit is called when a set is read without updating it.
+	CacheHit(ctx context.Context, key string)
+	// CacheMiss is called when a set has to be updated before it is read.
+	CacheMiss(ctx context.Context, key string)
+	// StaleServe is called when an expiring set is read while it is updated by someone else.
+	StaleServe(ctx context.Context, key string)
+	RefreshStart(ctx context.Context, key string)
+	// RefreshEnd is called after RefreshStart. count is the number of members after the refresh, or -1 when it is unknown.
+	RefreshEnd(ctx context.Context, key string, duration time.Duration, count int64, err error)
+	StoreCall(ctx context.Context, method string, key string, duration time.Duration, err error)
+}
+
+// NopObserver ignores all events. Embed it to implement only a part of Observer.
+type NopObserver struct{}
+
+func (NopObserver) CacheHit(ctx context.Context, key string)     {}
+func (NopObserver) CacheMiss(ctx context.Context, key string)    {}
+func (NopObserver) StaleServe(ctx context.Context, key string)   {}
+func (NopObserver) RefreshStart(ctx context.Context, key string) {}
+func (NopObserver) RefreshEnd(ctx context.Context, key string, duration time.Duration, count int64, err error) {
+}
+func (NopObserver) StoreCall(ctx context.Context, method string, key string, duration time.Duration, err error) {
+}
+
+type observable interface {
+	observer() Observer
+}
+
+// StoreWrapper is implemented by stores decorating another store, so the observer is found through them.
+type StoreWrapper interface {
+	Unwrap() Store
+}
+
+// observerOf returns the observer attached to store by NewObservedStore
+func observerOf(store Store) (Observer, bool) {
+	for store != nil {
+		if o, ok := store.(observable); ok {
+			return o.observer(), true
+		}
+		w, ok := store.(StoreWrapper)
+		if !ok {
+			break
+		}
+		store = w.Unwrap()
+	}
+	return NopObserver{}, false
+}
+
+// observeUpdate calls update of key and reports it as a refresh
+func observeUpdate(ctx context.Context, store Store, key string, update func(ctx context.Context) error) error {
+	o, ok := observerOf(store)
+	if !ok {
+		return update(ctx)
+	}
+
+	o.RefreshStart(ctx, key)
+	start := time.Now()
+	err := update(ctx)
+	duration := time.Since(start)
+
+	count := int64(-1)
+	if err == nil {
+		if c, cerr := store.Count(ctx, key); cerr == nil {
+			count = c
+		}
+	}
+	o.RefreshEnd(ctx, key, duration, count, err)
+	return err
+}
+
+// SetName returns a low-cardinality name of the set stored at key, e.g. `region@2` of `region@2:tokyo` and `union` of composed sets.
+// It is a heuristic: a suffix containing ':' is partly kept. Composed sets are cut before their arguments,
+// which contain keys of children, so `svc:union` of `svc:union[SUM;1,1](12:svc:tokyo,12:svc:osaka)` is kept.
+func SetName(key string) string {
+	if i := strings.IndexAny(key, "[(#"); i >= 0 {
+		return key[:i]
+	}
+	if i := strings.LastIndex(key, ":"); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
@@ -0,0 +1,125 @@
+package redblocks_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+type recordingObserver struct {
+	redblocks.NopObserver
+	mu     sync.Mutex
+	events []string
+	counts []int64
+	calls  map[string]int
+}
+
+func (o *recordingObserver) record(event string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *recordingObserver) CacheHit(ctx context.Context, key string) {
+	o.record("hit " + key)
+}
+
+func (o *recordingObserver) CacheMiss(ctx context.Context, key string) {
+	o.record("miss " + key)
+}
+
+func (o *recordingObserver) RefreshStart(ctx context.Context, key string) {
+	o.record("start " + key)
+}
+
+func (o *recordingObserver) RefreshEnd(ctx context.Context, key string, duration time.Duration, count int64, err error) {
+	o.record("end " + key)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.counts = append(o.counts, count)
+}
+
+func (o *recordingObserver) StoreCall(ctx context.Context, method string, key string, duration time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.calls == nil {
+		o.calls = map[string]int{}
+	}
+	o.calls[method]++
+}
+
+func TestObserver(t *testing.T) {
+	o := &recordingObserver{}
+	store := redblocks.NewObservedStore(redblocks.NewMemoryStore(), o)
+	tokyo := redblocks.Compose(NewScoredSet("tokyo", []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}), store, redblocks.WithKey("region"))
+	osaka := redblocks.Compose(NewScoredSet("osaka", []redblocks.IDWithScore{{ID: "3", Score: 3}}), store, redblocks.WithKey("region"))
+	union := redblocks.NewUnionSetWithOptions(store, time.Second*100, time.Second*10, nil, redblocks.Sum, []redblocks.OperatorOption{redblocks.WithChildConcurrency(1)}, tokyo, osaka)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := union.IDs(ctx); err != nil {
+			t.Error(err)
+		}
+	}
+
+	want := []string{
+		"miss " + union.Key(),
+		"miss region:tokyo",
+		"start region:tokyo",
+		"end region:tokyo",
+		"miss region:osaka",
+		"start region:osaka",
+		"end region:osaka",
+		"start " + union.Key(),
+		"end " + union.Key(),
+		"hit " + union.Key(),
+	}
+	if diff := cmp.Diff(o.events, want); diff != "" {
+		t.Errorf(diff)
+	}
+	if diff := cmp.Diff(o.counts, []int64{2, 1, 3}); diff != "" {
+		t.Errorf(diff)
+	}
+	if o.calls["Unionstore"] != 1 || o.calls["GetIDs"] != 2 {
+		t.Errorf("unexpected store calls: %v", o.calls)
+	}
+}
+
+func TestSetName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "region@2:tokyo", want: "region@2"},
+		{key: "service:prod:region:tokyo", want: "service:prod:region"},
+		{key: "union[SUM;1,1](12:region:tokyo,12:region:osaka)", want: "union"},
+		{key: "subtraction#0123abcd", want: "subtraction"},
+		{key: "svc:union[SUM;1,1](37:svc:redblocks_test.scoredSetImp:tokyo)", want: "svc:union"},
+		{key: "svc:subtraction#0123abcd", want: "svc:subtraction"},
+		{key: "region", want: "region"},
+	}
+
+	for _, test := range tests {
+		if diff := cmp.Diff(redblocks.SetName(test.key), test.want); diff != "" {
+			t.Errorf(diff)
+		}
+	}
+}
+
+func TestSetNamePrefixed(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	tokyo := redblocks.Compose(NewScoredSet("tokyo", nil), store, redblocks.WithPrefix("svc"), redblocks.WithKey("region"))
+	osaka := redblocks.Compose(NewScoredSet("osaka", nil), store, redblocks.WithPrefix("svc"), redblocks.WithKey("region"))
+	union := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, tokyo, osaka)
+
+	if diff := cmp.Diff(redblocks.SetName(union.Key()), "svc:union"); diff != "" {
+		t.Errorf(diff)
+	}
+	if diff := cmp.Diff(redblocks.SetName(tokyo.Key()), "svc:region"); diff != "" {
+		t.Errorf(diff)
+	}
+}
@@ -46,3 +46,7 @@ func (s aliasImp) Available(ctx context.Context) (bool, error) {
 func (a aliasImp) Warmup(ctx context.Context) error {
 	return nil
 }
+
+func (a aliasImp) Describe() Operation {
+	return Operation{Operator: "alias"}
+}
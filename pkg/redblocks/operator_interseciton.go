@@ -2,10 +2,9 @@ package redblocks
 
 import (
 	"context"
-	"strings"
 	"time"
 
-	"github.com/srvc/fail"
+	"github.com/rerost/redblocks-go/internal/fail"
 )
 
 type intersectionSetImp struct {
@@ -15,13 +14,24 @@ type intersectionSetImp struct {
 	notAvailableTTL time.Duration
 	aggregate       Aggregate
 	weights         []float64
+	opt             OperatorOption
 }
 
 func NewIntersectionSet(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, weights []float64, aggregate Aggregate, sets ...ComposedSet) ComposedSet {
-	return ComposeIDs(ComposeWarmup(NewIntersectionSetImp(store, cacheTime, notAvailableTTL, weights, aggregate, sets...), store), store)
+	return ComposeIDs(ComposeWarmup(NewIntersectionSetImpWithOptions(store, cacheTime, notAvailableTTL, weights, aggregate, nil, sets...), store), store)
+}
+
+// NewIntersectionSetWithOptions is NewIntersectionSet with OperatorOption, which controls how children are warmed up.
+func NewIntersectionSetWithOptions(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, weights []float64, aggregate Aggregate, opts []OperatorOption, sets ...ComposedSet) ComposedSet {
+	return ComposeIDs(ComposeWarmup(NewIntersectionSetImpWithOptions(store, cacheTime, notAvailableTTL, weights, aggregate, opts, sets...), store), store)
 }
 
 func NewIntersectionSetImp(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, weights []float64, aggregate Aggregate, sets ...ComposedSet) WithUpdate {
+	return NewIntersectionSetImpWithOptions(store, cacheTime, notAvailableTTL, weights, aggregate, nil, sets...)
+}
+
+func NewIntersectionSetImpWithOptions(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, weights []float64, aggregate Aggregate, opts []OperatorOption, sets ...ComposedSet) WithUpdate {
+	opt, _ := OperatorOptionsToOperatorOption(opts)
 	return intersectionSetImp{
 		store:           store,
 		sets:            sets,
@@ -29,6 +39,7 @@ func NewIntersectionSetImp(store Store, cacheTime time.Duration, notAvailableTTL
 		notAvailableTTL: notAvailableTTL,
 		aggregate:       aggregate,
 		weights:         weights,
+		opt:             opt,
 	}
 }
 
@@ -52,27 +63,33 @@ func (s intersectionSetImp) NotAvailableTTL() time.Duration {
 }
 
 func (s intersectionSetImp) Key() string {
-	keys := make([]string, len(s.sets), len(s.sets))
-	for i, set := range s.sets {
-		keys[i] = set.Key()
-	}
-	return strings.Join(keys, "&")
+	return boundedKey(s.KeyPrefix(), "intersection", s.CanonicalKey())
+}
+
+func (s intersectionSetImp) CanonicalKey() string {
+	return prefixKey(s.KeyPrefix(), canonicalKey("intersection", []string{s.aggregate.String(), weightsParam(s.weights, len(s.sets))}, childKeys(s.sets)))
+}
+
+func (s intersectionSetImp) KeyPrefix() string {
+	return sharedPrefix(s.Children())
 }
 
 func (s intersectionSetImp) Update(ctx context.Context) error {
-	keys := make([]string, len(s.sets), len(s.sets))
-	for i, set := range s.sets {
-		keys[i] = set.Key()
+	if err := unionOnly(s.opt); err != nil {
+		return fail.Wrap(err)
 	}
-	for _, set := range s.sets {
-		set.Warmup(ctx)
+	if err := warmupChildren(ctx, s.sets, s.opt); err != nil {
+		return fail.Wrap(err)
 	}
+	keys := childKeys(s.sets)
 
-	err := s.store.Interstore(ctx, s.Key(), s.CacheTime(), s.weights, s.aggregate, keys...)
+	err := observeUpdate(ctx, s.store, s.Key(), func(ctx context.Context) error {
+		return s.store.Interstore(ctx, s.Key(), s.CacheTime(), s.weights, s.aggregate, keys...)
+	})
 	if err != nil {
 		return fail.Wrap(err)
 	}
-	return nil
+	return fail.Wrap(recordDependents(ctx, s.store, s.Key(), s.Children(), s.CacheTime()))
 }
 
 func (c intersectionSetImp) Available(ctx context.Context) (bool, error) {
@@ -94,3 +111,11 @@ func (c intersectionSetImp) Available(ctx context.Context) (bool, error) {
 
 	return true, nil
 }
+
+func (s intersectionSetImp) Children() []ComposedSet {
+	return s.sets
+}
+
+func (s intersectionSetImp) Describe() Operation {
+	return Operation{Operator: "intersection", Weights: effectiveWeights(s.weights, len(s.sets)), Aggregate: s.aggregate.String()}
+}
@@ -0,0 +1,107 @@
+package redblocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+type scoreFilterSetImp struct {
+	store           Store
+	set             ComposedSet
+	min             ScoreBound
+	max             ScoreBound
+	cacheTime       time.Duration
+	notAvailableTTL time.Duration
+}
+
+// NewScoreFilterSet keeps members of set whose score is between min and max.
+func NewScoreFilterSet(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, min ScoreBound, max ScoreBound, set ComposedSet) ComposedSet {
+	return ComposeIDs(ComposeWarmup(NewScoreFilterSetImp(store, cacheTime, notAvailableTTL, min, max, set), store), store)
+}
+
+func NewScoreFilterSetImp(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, min ScoreBound, max ScoreBound, set ComposedSet) WithUpdate {
+	return scoreFilterSetImp{
+		store:           store,
+		set:             set,
+		min:             min,
+		max:             max,
+		cacheTime:       cacheTime,
+		notAvailableTTL: notAvailableTTL,
+	}
+}
+
+func (s scoreFilterSetImp) KeySuffix() string {
+	return ""
+}
+
+func (s scoreFilterSetImp) Get(ctx context.Context) ([]IDWithScore, error) {
+	err := s.Update(ctx)
+	if err != nil {
+		return []IDWithScore{}, fail.Wrap(err)
+	}
+	return s.store.GetIDsWithScore(ctx, s.Key(), 0, -1, Asc)
+}
+
+func (s scoreFilterSetImp) CacheTime() time.Duration {
+	return s.cacheTime
+}
+
+func (s scoreFilterSetImp) NotAvailableTTL() time.Duration {
+	return s.notAvailableTTL
+}
+
+func (s scoreFilterSetImp) Key() string {
+	return boundedKey(s.KeyPrefix(), "filter", s.CanonicalKey())
+}
+
+func (s scoreFilterSetImp) CanonicalKey() string {
+	return prefixKey(s.KeyPrefix(), canonicalKey("filter", []string{s.min.String(), s.max.String()}, childKeys(s.Children())))
+}
+
+func (s scoreFilterSetImp) KeyPrefix() string {
+	return sharedPrefix(s.Children())
+}
+
+func (s scoreFilterSetImp) Update(ctx context.Context) error {
+	if err := s.set.Warmup(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+
+	err := observeUpdate(ctx, s.store, s.Key(), func(ctx context.Context) error {
+		return s.store.FilterByScore(ctx, s.Key(), s.CacheTime(), s.min, s.max, s.set.Key())
+	})
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	return fail.Wrap(recordDependents(ctx, s.store, s.Key(), s.Children(), s.CacheTime()))
+}
+
+func (c scoreFilterSetImp) Available(ctx context.Context) (bool, error) {
+	exists, err := c.store.Exists(ctx, c.Key())
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	ttl, err := c.store.TTL(ctx, c.Key())
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	if ttl < c.notAvailableTTL {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (s scoreFilterSetImp) Children() []ComposedSet {
+	return []ComposedSet{s.set}
+}
+
+func (s scoreFilterSetImp) Describe() Operation {
+	return Operation{Operator: "filter", Params: []string{s.min.String(), s.max.String()}}
+}
@@ -0,0 +1,73 @@
+package redblocks_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func TestScoreFilterSet(t *testing.T) {
+	stores := map[string]redblocks.Store{
+		"memory": redblocks.NewMemoryStore(),
+		"redis":  redblocks.NewRedisStore(newPool()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testScoreFilterSet(t, store)
+		})
+	}
+}
+
+func testScoreFilterSet(t *testing.T, store redblocks.Store) {
+	ctx := context.Background()
+	views := redblocks.Compose(NewScoredSet("TestScoreFilterSetViews", []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}, {ID: "c", Score: 3}, {ID: "d", Score: 4}}), store)
+	top := redblocks.NewTopNSet(store, time.Second*100, time.Second*10, 3, redblocks.Asc, views)
+
+	tests := []struct {
+		name     string
+		set      redblocks.ComposedSet
+		expected []redblocks.ID
+	}{
+		{
+			name:     "exclusive min",
+			set:      redblocks.NewScoreFilterSet(store, time.Second*100, time.Second*10, redblocks.Exclusive(1), redblocks.PositiveInf, views),
+			expected: []redblocks.ID{"b", "c", "d"},
+		},
+		{
+			name:     "inclusive min and exclusive max",
+			set:      redblocks.NewScoreFilterSet(store, time.Second*100, time.Second*10, redblocks.Inclusive(2), redblocks.Exclusive(4), views),
+			expected: []redblocks.ID{"b", "c"},
+		},
+		{
+			name:     "inclusive max",
+			set:      redblocks.NewScoreFilterSet(store, time.Second*100, time.Second*10, redblocks.NegativeInf, redblocks.Inclusive(2), views),
+			expected: []redblocks.ID{"a", "b"},
+		},
+		{
+			name:     "empty",
+			set:      redblocks.NewScoreFilterSet(store, time.Second*100, time.Second*10, redblocks.Exclusive(2), redblocks.Exclusive(3), views),
+			expected: []redblocks.ID{},
+		},
+		{
+			name:     "top",
+			set:      redblocks.NewScoreFilterSet(store, time.Second*100, time.Second*10, redblocks.Exclusive(1), redblocks.PositiveInf, top),
+			expected: []redblocks.ID{"b", "c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids, err := test.set.IDs(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(ids, test.expected, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf(diff)
+			}
+		})
+	}
+}
@@ -4,34 +4,42 @@ import (
 	"context"
 	"time"
 
-	"github.com/srvc/fail"
+	"github.com/rerost/redblocks-go/internal/fail"
 )
 
 type subtractionSetImp struct {
 	store           Store
-	set1            ComposedSet
-	set2            ComposedSet
+	set             ComposedSet
+	subtrahends     []ComposedSet
 	cacheTime       time.Duration
 	notAvailableTTL time.Duration
+	opt             OperatorOption
 }
 
-// NewSubtractionSet return set1 - set2
-// WARING: This function is experimental.
-// Because
-// - Slow
-// - set2's score needs to be much larger than set1' sscore
-// - set2's score needs to be a negative value
-func NewSubtractionSet(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, set1 ComposedSet, set2 ComposedSet) ComposedSet {
-	return ComposeIDs(ComposeWarmup(NewSubtractionSetImp(store, cacheTime, notAvailableTTL, set1, set2), store), store)
+// NewSubtractionSet return set - (subtrahends[0] | subtrahends[1] | ...)
+// Scores of set are kept whatever scores subtrahends have.
+func NewSubtractionSet(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, set ComposedSet, subtrahends ...ComposedSet) ComposedSet {
+	return ComposeIDs(ComposeWarmup(NewSubtractionSetImpWithOptions(store, cacheTime, notAvailableTTL, set, nil, subtrahends...), store), store)
 }
 
-func NewSubtractionSetImp(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, set1 ComposedSet, set2 ComposedSet) WithUpdate {
+// NewSubtractionSetWithOptions is NewSubtractionSet with OperatorOption, which controls how children are warmed up.
+func NewSubtractionSetWithOptions(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, set ComposedSet, opts []OperatorOption, subtrahends ...ComposedSet) ComposedSet {
+	return ComposeIDs(ComposeWarmup(NewSubtractionSetImpWithOptions(store, cacheTime, notAvailableTTL, set, opts, subtrahends...), store), store)
+}
+
+func NewSubtractionSetImp(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, set ComposedSet, subtrahends ...ComposedSet) WithUpdate {
+	return NewSubtractionSetImpWithOptions(store, cacheTime, notAvailableTTL, set, nil, subtrahends...)
+}
+
+func NewSubtractionSetImpWithOptions(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, set ComposedSet, opts []OperatorOption, subtrahends ...ComposedSet) WithUpdate {
+	opt, _ := OperatorOptionsToOperatorOption(opts)
 	return subtractionSetImp{
 		store:           store,
-		set1:            set1,
-		set2:            set2,
+		set:             set,
+		subtrahends:     subtrahends,
 		cacheTime:       cacheTime,
 		notAvailableTTL: notAvailableTTL,
+		opt:             opt,
 	}
 }
 
@@ -55,23 +63,34 @@ func (s subtractionSetImp) NotAvailableTTL() time.Duration {
 }
 
 func (s subtractionSetImp) Key() string {
-	return s.set1.Key() + "-" + s.set2.Key()
+	return boundedKey(s.KeyPrefix(), "subtraction", s.CanonicalKey())
+}
+
+func (s subtractionSetImp) CanonicalKey() string {
+	return prefixKey(s.KeyPrefix(), canonicalKey("subtraction", nil, childKeys(s.Children())))
+}
+
+func (s subtractionSetImp) KeyPrefix() string {
+	return sharedPrefix(s.Children())
 }
 
 func (s subtractionSetImp) Update(ctx context.Context) error {
-	if err := s.set1.Warmup(ctx); err != nil {
+	if err := unionOnly(s.opt); err != nil {
 		return fail.Wrap(err)
 	}
-	if err := s.set2.Warmup(ctx); err != nil {
+	if err := warmupChildren(ctx, s.Children(), s.opt); err != nil {
 		return fail.Wrap(err)
 	}
+	keys := childKeys(s.subtrahends)
 
-	err := s.store.Subtraction(ctx, s.Key(), s.CacheTime(), s.set1.Key(), s.set2.Key())
+	err := observeUpdate(ctx, s.store, s.Key(), func(ctx context.Context) error {
+		return s.store.Subtraction(ctx, s.Key(), s.CacheTime(), s.set.Key(), keys...)
+	})
 	if err != nil {
 		return fail.Wrap(err)
 	}
 
-	return nil
+	return fail.Wrap(recordDependents(ctx, s.store, s.Key(), s.Children(), s.CacheTime()))
 }
 
 func (c subtractionSetImp) Available(ctx context.Context) (bool, error) {
@@ -93,3 +112,11 @@ func (c subtractionSetImp) Available(ctx context.Context) (bool, error) {
 
 	return true, nil
 }
+
+func (s subtractionSetImp) Children() []ComposedSet {
+	return append([]ComposedSet{s.set}, s.subtrahends...)
+}
+
+func (s subtractionSetImp) Describe() Operation {
+	return Operation{Operator: "subtraction"}
+}
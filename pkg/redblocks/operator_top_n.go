@@ -0,0 +1,108 @@
+package redblocks
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+type topNSetImp struct {
+	store           Store
+	set             ComposedSet
+	n               int64
+	order           Order
+	cacheTime       time.Duration
+	notAvailableTTL time.Duration
+}
+
+// NewTopNSet keeps the first n members of set in order. Desc keeps the n highest scores.
+func NewTopNSet(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, n int64, order Order, set ComposedSet) ComposedSet {
+	return ComposeIDs(ComposeWarmup(NewTopNSetImp(store, cacheTime, notAvailableTTL, n, order, set), store), store)
+}
+
+func NewTopNSetImp(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, n int64, order Order, set ComposedSet) WithUpdate {
+	return topNSetImp{
+		store:           store,
+		set:             set,
+		n:               n,
+		order:           order,
+		cacheTime:       cacheTime,
+		notAvailableTTL: notAvailableTTL,
+	}
+}
+
+func (s topNSetImp) KeySuffix() string {
+	return ""
+}
+
+func (s topNSetImp) Get(ctx context.Context) ([]IDWithScore, error) {
+	err := s.Update(ctx)
+	if err != nil {
+		return []IDWithScore{}, fail.Wrap(err)
+	}
+	return s.store.GetIDsWithScore(ctx, s.Key(), 0, -1, Asc)
+}
+
+func (s topNSetImp) CacheTime() time.Duration {
+	return s.cacheTime
+}
+
+func (s topNSetImp) NotAvailableTTL() time.Duration {
+	return s.notAvailableTTL
+}
+
+func (s topNSetImp) Key() string {
+	return boundedKey(s.KeyPrefix(), "top", s.CanonicalKey())
+}
+
+func (s topNSetImp) CanonicalKey() string {
+	return prefixKey(s.KeyPrefix(), canonicalKey("top", []string{strconv.FormatInt(s.n, 10), s.order.String()}, childKeys(s.Children())))
+}
+
+func (s topNSetImp) KeyPrefix() string {
+	return sharedPrefix(s.Children())
+}
+
+func (s topNSetImp) Update(ctx context.Context) error {
+	if err := s.set.Warmup(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+
+	err := observeUpdate(ctx, s.store, s.Key(), func(ctx context.Context) error {
+		return s.store.TopN(ctx, s.Key(), s.CacheTime(), s.n, s.order, s.set.Key())
+	})
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	return fail.Wrap(recordDependents(ctx, s.store, s.Key(), s.Children(), s.CacheTime()))
+}
+
+func (c topNSetImp) Available(ctx context.Context) (bool, error) {
+	exists, err := c.store.Exists(ctx, c.Key())
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	ttl, err := c.store.TTL(ctx, c.Key())
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	if ttl < c.notAvailableTTL {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (s topNSetImp) Children() []ComposedSet {
+	return []ComposedSet{s.set}
+}
+
+func (s topNSetImp) Describe() Operation {
+	return Operation{Operator: "top", Params: []string{strconv.FormatInt(s.n, 10), s.order.String()}}
+}
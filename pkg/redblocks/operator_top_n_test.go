@@ -0,0 +1,75 @@
+package redblocks_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func TestTopNSet(t *testing.T) {
+	stores := map[string]redblocks.Store{
+		"memory": redblocks.NewMemoryStore(),
+		"redis":  redblocks.NewRedisStore(newPool()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testTopNSet(t, store)
+		})
+	}
+}
+
+func testTopNSet(t *testing.T, store redblocks.Store) {
+	ctx := context.Background()
+	views := redblocks.Compose(NewScoredSet("TestTopNSetViews", []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}, {ID: "c", Score: 3}, {ID: "d", Score: 4}}), store)
+
+	tests := []struct {
+		name     string
+		set      redblocks.ComposedSet
+		expected []redblocks.IDWithScore
+	}{
+		{
+			name:     "top",
+			set:      redblocks.NewTopNSet(store, time.Second*100, time.Second*10, 3, redblocks.Desc, views),
+			expected: []redblocks.IDWithScore{{ID: "b", Score: 2}, {ID: "c", Score: 3}, {ID: "d", Score: 4}},
+		},
+		{
+			name:     "bottom",
+			set:      redblocks.NewTopNSet(store, time.Second*100, time.Second*10, 1, redblocks.Asc, views),
+			expected: []redblocks.IDWithScore{{ID: "a", Score: 1}},
+		},
+		{
+			name:     "more than members",
+			set:      redblocks.NewTopNSet(store, time.Second*100, time.Second*10, 10, redblocks.Desc, views),
+			expected: []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}, {ID: "c", Score: 3}, {ID: "d", Score: 4}},
+		},
+		{
+			name:     "zero",
+			set:      redblocks.NewTopNSet(store, time.Second*100, time.Second*10, 0, redblocks.Desc, views),
+			expected: []redblocks.IDWithScore{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idsWithScore, err := test.set.IDsWithScore(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(idsWithScore, test.expected, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf(diff)
+			}
+		})
+	}
+
+	desc, err := tests[0].set.IDsWithScore(ctx, redblocks.WithOrder(redblocks.Desc))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(desc, []redblocks.IDWithScore{{ID: "d", Score: 4}, {ID: "c", Score: 3}, {ID: "b", Score: 2}}); diff != "" {
+		t.Errorf(diff)
+	}
+}
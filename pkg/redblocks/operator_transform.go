@@ -0,0 +1,256 @@
+package redblocks
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+// ScoreTransform maps scores of all members of a set at once, so it can use statistics such as min and max.
+type ScoreTransform interface {
+	// Name is a part of the key of the transformed set, so it includes parameters, e.g. `scale(2)`.
+	Name() string
+	Transform(idsWithScore []IDWithScore) []IDWithScore
+}
+
+type transformSetImp struct {
+	store           Store
+	set             ComposedSet
+	transforms      []ScoreTransform
+	cacheTime       time.Duration
+	notAvailableTTL time.Duration
+}
+
+// NewTransformSet returns a copy of set whose scores are mapped by transforms in order, e.g. MinMax() then Scale(10).
+// Members are ordered by the new scores.
+func NewTransformSet(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, set ComposedSet, transforms ...ScoreTransform) ComposedSet {
+	return ComposeIDs(ComposeWarmup(NewTransformSetImp(store, cacheTime, notAvailableTTL, set, transforms...), store), store)
+}
+
+func NewTransformSetImp(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, set ComposedSet, transforms ...ScoreTransform) WithUpdate {
+	return transformSetImp{
+		store:           store,
+		set:             set,
+		transforms:      transforms,
+		cacheTime:       cacheTime,
+		notAvailableTTL: notAvailableTTL,
+	}
+}
+
+func (s transformSetImp) KeySuffix() string {
+	return ""
+}
+
+func (s transformSetImp) Get(ctx context.Context) ([]IDWithScore, error) {
+	err := s.Update(ctx)
+	if err != nil {
+		return []IDWithScore{}, fail.Wrap(err)
+	}
+	return s.store.GetIDsWithScore(ctx, s.Key(), 0, -1, Asc)
+}
+
+func (s transformSetImp) CacheTime() time.Duration {
+	return s.cacheTime
+}
+
+func (s transformSetImp) NotAvailableTTL() time.Duration {
+	return s.notAvailableTTL
+}
+
+func (s transformSetImp) Key() string {
+	return boundedKey(s.KeyPrefix(), "transform", s.CanonicalKey())
+}
+
+func (s transformSetImp) CanonicalKey() string {
+	return prefixKey(s.KeyPrefix(), canonicalKey("transform", s.names(), childKeys(s.Children())))
+}
+
+func (s transformSetImp) KeyPrefix() string {
+	return sharedPrefix(s.Children())
+}
+
+func (s transformSetImp) names() []string {
+	names := make([]string, len(s.transforms), len(s.transforms))
+	for i, t := range s.transforms {
+		names[i] = t.Name()
+	}
+	return names
+}
+
+func (s transformSetImp) Update(ctx context.Context) error {
+	if err := s.set.Warmup(ctx); err != nil {
+		return fail.Wrap(err)
+	}
+
+	err := observeUpdate(ctx, s.store, s.Key(), func(ctx context.Context) error {
+		idsWithScore, err := s.store.GetIDsWithScore(ctx, s.set.Key(), 0, -1, Asc)
+		if err != nil {
+			return fail.Wrap(err)
+		}
+		for _, t := range s.transforms {
+			idsWithScore = t.Transform(idsWithScore)
+		}
+		return s.store.Save(ctx, s.Key(), idsWithScore, s.CacheTime())
+	})
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	return fail.Wrap(recordDependents(ctx, s.store, s.Key(), s.Children(), s.CacheTime()))
+}
+
+func (c transformSetImp) Available(ctx context.Context) (bool, error) {
+	exists, err := c.store.Exists(ctx, c.Key())
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	ttl, err := c.store.TTL(ctx, c.Key())
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	if ttl < c.notAvailableTTL {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (s transformSetImp) Children() []ComposedSet {
+	return []ComposedSet{s.set}
+}
+
+func (s transformSetImp) Describe() Operation {
+	return Operation{Operator: "transform", Params: s.names()}
+}
+
+// mapScores returns a copy of idsWithScore with scores mapped by f
+func mapScores(idsWithScore []IDWithScore, f func(score float64) float64) []IDWithScore {
+	r := make([]IDWithScore, len(idsWithScore), len(idsWithScore))
+	for i, v := range idsWithScore {
+		r[i] = IDWithScore{ID: v.ID, Score: f(v.Score)}
+	}
+	return r
+}
+
+type scoreFunc struct {
+	name string
+	f    func(score float64) float64
+}
+
+func (t scoreFunc) Name() string {
+	return t.name
+}
+
+func (t scoreFunc) Transform(idsWithScore []IDWithScore) []IDWithScore {
+	return mapScores(idsWithScore, t.f)
+}
+
+// Scale multiplies scores by factor.
+func Scale(factor float64) ScoreTransform {
+	return scoreFunc{name: "scale(" + strconv.FormatFloat(factor, 'g', -1, 64) + ")", f: func(score float64) float64 { return score * factor }}
+}
+
+// Offset adds delta to scores.
+func Offset(delta float64) ScoreTransform {
+	return scoreFunc{name: "offset(" + strconv.FormatFloat(delta, 'g', -1, 64) + ")", f: func(score float64) float64 { return score + delta }}
+}
+
+// Log maps scores to log(1 + score). Negative scores are treated as 0.
+func Log() ScoreTransform {
+	return scoreFunc{name: "log", f: func(score float64) float64 { return math.Log1p(math.Max(score, 0)) }}
+}
+
+type minMax struct{}
+
+// MinMax maps scores linearly to [0, 1]. All scores are 0 when they are the same.
+func MinMax() ScoreTransform {
+	return minMax{}
+}
+
+func (minMax) Name() string {
+	return "minmax"
+}
+
+func (minMax) Transform(idsWithScore []IDWithScore) []IDWithScore {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range idsWithScore {
+		min = math.Min(min, v.Score)
+		max = math.Max(max, v.Score)
+	}
+	return mapScores(idsWithScore, func(score float64) float64 {
+		if max == min {
+			return 0
+		}
+		return (score - min) / (max - min)
+	})
+}
+
+type zScore struct{}
+
+// ZScore maps scores to the number of standard deviations from the mean. All scores are 0 when they are the same.
+func ZScore() ScoreTransform {
+	return zScore{}
+}
+
+func (zScore) Name() string {
+	return "zscore"
+}
+
+func (zScore) Transform(idsWithScore []IDWithScore) []IDWithScore {
+	if len(idsWithScore) == 0 {
+		return idsWithScore
+	}
+	var sum float64
+	for _, v := range idsWithScore {
+		sum += v.Score
+	}
+	mean := sum / float64(len(idsWithScore))
+	var variance float64
+	for _, v := range idsWithScore {
+		variance += (v.Score - mean) * (v.Score - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(idsWithScore)))
+	return mapScores(idsWithScore, func(score float64) float64 {
+		if stddev == 0 {
+			return 0
+		}
+		return (score - mean) / stddev
+	})
+}
+
+type decay struct {
+	halfLife time.Duration
+	now      func() time.Time
+}
+
+// Decay maps scores which are unix timestamps in seconds to 0.5^(age/halfLife), so newer members score higher, at most 1.
+// Age is measured at the time of the update, so scores get older until the next update. now can be nil to use time.Now.
+// halfLife must be positive, otherwise scores would be NaN or infinite.
+func Decay(halfLife time.Duration, now func() time.Time) (ScoreTransform, error) {
+	if halfLife <= 0 {
+		return nil, fail.Wrap(fail.New("halfLife must be positive"), fail.WithParam("halfLife", halfLife))
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return decay{halfLife: halfLife, now: now}, nil
+}
+
+func (t decay) Name() string {
+	return "decay(" + t.halfLife.String() + ")"
+}
+
+func (t decay) Transform(idsWithScore []IDWithScore) []IDWithScore {
+	now := float64(t.now().UnixNano()) / float64(time.Second)
+	halfLife := t.halfLife.Seconds()
+	return mapScores(idsWithScore, func(score float64) float64 {
+		age := math.Max(now-score, 0)
+		return math.Exp2(-age / halfLife)
+	})
+}
@@ -0,0 +1,88 @@
+package redblocks_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func TestTransformSet(t *testing.T) {
+	stores := map[string]redblocks.Store{
+		"memory": redblocks.NewMemoryStore(),
+		"redis":  redblocks.NewRedisStore(newPool()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testTransformSet(t, store)
+		})
+	}
+}
+
+func testTransformSet(t *testing.T, store redblocks.Store) {
+	ctx := context.Background()
+	views := redblocks.Compose(NewScoredSet("TestTransformSetViews", []redblocks.IDWithScore{{ID: "1", Score: 10}, {ID: "2", Score: 20}, {ID: "3", Score: 30}}), store)
+
+	now := time.Unix(1000, 0)
+	posted := redblocks.Compose(NewScoredSet("TestTransformSetPosted", []redblocks.IDWithScore{{ID: "1", Score: 1000}, {ID: "2", Score: 1000 - 3600}}), store)
+	decay, err := redblocks.Decay(time.Hour, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		set      redblocks.ComposedSet
+		expected []redblocks.IDWithScore
+	}{
+		{
+			name:     "scale and offset",
+			set:      redblocks.NewTransformSet(store, time.Second*100, time.Second*10, views, redblocks.Scale(2), redblocks.Offset(-5)),
+			expected: []redblocks.IDWithScore{{ID: "1", Score: 15}, {ID: "2", Score: 35}, {ID: "3", Score: 55}},
+		},
+		{
+			name:     "minmax",
+			set:      redblocks.NewTransformSet(store, time.Second*100, time.Second*10, views, redblocks.MinMax()),
+			expected: []redblocks.IDWithScore{{ID: "1", Score: 0}, {ID: "2", Score: 0.5}, {ID: "3", Score: 1}},
+		},
+		{
+			name:     "zscore",
+			set:      redblocks.NewTransformSet(store, time.Second*100, time.Second*10, views, redblocks.ZScore()),
+			expected: []redblocks.IDWithScore{{ID: "1", Score: -math.Sqrt(1.5)}, {ID: "2", Score: 0}, {ID: "3", Score: math.Sqrt(1.5)}},
+		},
+		{
+			name:     "log",
+			set:      redblocks.NewTransformSet(store, time.Second*100, time.Second*10, views, redblocks.Offset(-11), redblocks.Log()),
+			expected: []redblocks.IDWithScore{{ID: "1", Score: 0}, {ID: "2", Score: math.Log(10)}, {ID: "3", Score: math.Log(20)}},
+		},
+		{
+			name:     "decay",
+			set:      redblocks.NewTransformSet(store, time.Second*100, time.Second*10, posted, decay),
+			expected: []redblocks.IDWithScore{{ID: "2", Score: 0.5}, {ID: "1", Score: 1}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idsWithScore, err := test.set.IDsWithScore(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(idsWithScore, test.expected, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf(diff)
+			}
+		})
+	}
+}
+
+func TestDecayHalfLife(t *testing.T) {
+	for _, halfLife := range []time.Duration{0, -time.Hour} {
+		if _, err := redblocks.Decay(halfLife, nil); err == nil {
+			t.Errorf("Expected not nil for %v", halfLife)
+		}
+	}
+}
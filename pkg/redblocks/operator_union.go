@@ -2,10 +2,9 @@ package redblocks
 
 import (
 	"context"
-	"strings"
 	"time"
 
-	"github.com/srvc/fail"
+	"github.com/rerost/redblocks-go/internal/fail"
 )
 
 type unionSetImp struct {
@@ -15,13 +14,24 @@ type unionSetImp struct {
 	notAvailableTTL time.Duration
 	aggregate       Aggregate
 	weights         []float64
+	opt             OperatorOption
 }
 
 func NewUnionSet(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, weights []float64, aggregate Aggregate, sets ...ComposedSet) ComposedSet {
-	return ComposeIDs(ComposeWarmup(NewUnionSetImp(store, cacheTime, notAvailableTTL, weights, aggregate, sets...), store), store)
+	return ComposeIDs(ComposeWarmup(NewUnionSetImpWithOptions(store, cacheTime, notAvailableTTL, weights, aggregate, nil, sets...), store), store)
+}
+
+// NewUnionSetWithOptions is NewUnionSet with OperatorOption, which controls how children are warmed up.
+func NewUnionSetWithOptions(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, weights []float64, aggregate Aggregate, opts []OperatorOption, sets ...ComposedSet) ComposedSet {
+	return ComposeIDs(ComposeWarmup(NewUnionSetImpWithOptions(store, cacheTime, notAvailableTTL, weights, aggregate, opts, sets...), store), store)
 }
 
 func NewUnionSetImp(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, weights []float64, aggregate Aggregate, sets ...ComposedSet) WithUpdate {
+	return NewUnionSetImpWithOptions(store, cacheTime, notAvailableTTL, weights, aggregate, nil, sets...)
+}
+
+func NewUnionSetImpWithOptions(store Store, cacheTime time.Duration, notAvailableTTL time.Duration, weights []float64, aggregate Aggregate, opts []OperatorOption, sets ...ComposedSet) WithUpdate {
+	opt, _ := OperatorOptionsToOperatorOption(opts)
 	return unionSetImp{
 		store:           store,
 		sets:            sets,
@@ -29,6 +39,7 @@ func NewUnionSetImp(store Store, cacheTime time.Duration, notAvailableTTL time.D
 		notAvailableTTL: notAvailableTTL,
 		aggregate:       aggregate,
 		weights:         weights,
+		opt:             opt,
 	}
 }
 
@@ -53,27 +64,30 @@ func (s unionSetImp) NotAvailableTTL() time.Duration {
 }
 
 func (s unionSetImp) Key() string {
-	keys := make([]string, len(s.sets), len(s.sets))
-	for i, set := range s.sets {
-		keys[i] = set.Key()
-	}
-	return strings.Join(keys, "|")
+	return boundedKey(s.KeyPrefix(), "union", s.CanonicalKey())
+}
+
+func (s unionSetImp) CanonicalKey() string {
+	return prefixKey(s.KeyPrefix(), canonicalKey("union", []string{s.aggregate.String(), weightsParam(s.weights, len(s.sets))}, childKeys(s.sets)))
+}
+
+func (s unionSetImp) KeyPrefix() string {
+	return sharedPrefix(s.Children())
 }
 
 func (s unionSetImp) Update(ctx context.Context) error {
-	keys := make([]string, len(s.sets), len(s.sets))
-	for i, set := range s.sets {
-		keys[i] = set.Key()
-	}
-	for _, set := range s.sets {
-		set.Warmup(ctx)
+	if err := warmupChildren(ctx, s.sets, s.opt); err != nil {
+		return fail.Wrap(err)
 	}
+	keys := childKeys(s.sets)
 
-	err := s.store.Unionstore(ctx, s.Key(), s.CacheTime(), s.weights, s.aggregate, keys...)
+	err := observeUpdate(ctx, s.store, s.Key(), func(ctx context.Context) error {
+		return s.store.Unionstore(ctx, s.Key(), s.CacheTime(), s.weights, s.aggregate, keys...)
+	})
 	if err != nil {
 		return fail.Wrap(err)
 	}
-	return nil
+	return fail.Wrap(recordDependents(ctx, s.store, s.Key(), s.Children(), s.CacheTime()))
 }
 
 func (c unionSetImp) Available(ctx context.Context) (bool, error) {
@@ -95,3 +109,11 @@ func (c unionSetImp) Available(ctx context.Context) (bool, error) {
 
 	return true, nil
 }
+
+func (s unionSetImp) Children() []ComposedSet {
+	return s.sets
+}
+
+func (s unionSetImp) Describe() Operation {
+	return Operation{Operator: "union", Weights: effectiveWeights(s.weights, len(s.sets)), Aggregate: s.aggregate.String()}
+}
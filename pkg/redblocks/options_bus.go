@@ -0,0 +1,60 @@
+package redblocks
+
+import (
+	"time"
+)
+
+type BusOption struct {
+	Channel       string
+	RetryInterval time.Duration   // Wait before reconnecting a lost subscription
+	PingInterval  time.Duration   // Interval of PING on an idle subscription of NewRedisBus. It is lost when nothing is received for twice as long.
+	OnError       func(err error) // Called when a subscription is lost or a message can not be decoded
+}
+
+func BusOptionsToBusOption(opts []BusOption) (BusOption, error) {
+	opt := BusOption{
+		Channel:       "redblocks:invalidation",
+		RetryInterval: time.Second,
+		PingInterval:  time.Second * 30,
+	}
+	for _, o := range opts {
+		if o.Channel != "" {
+			opt.Channel = o.Channel
+		}
+		if o.RetryInterval != 0 {
+			opt.RetryInterval = o.RetryInterval
+		}
+		if o.PingInterval != 0 {
+			opt.PingInterval = o.PingInterval
+		}
+		if o.OnError != nil {
+			opt.OnError = o.OnError
+		}
+	}
+
+	return opt, nil
+}
+
+func WithBusChannel(channel string) BusOption {
+	return BusOption{
+		Channel: channel,
+	}
+}
+
+func WithBusRetryInterval(interval time.Duration) BusOption {
+	return BusOption{
+		RetryInterval: interval,
+	}
+}
+
+func WithBusPingInterval(interval time.Duration) BusOption {
+	return BusOption{
+		PingInterval: interval,
+	}
+}
+
+func WithBusOnError(onError func(err error)) BusOption {
+	return BusOption{
+		OnError: onError,
+	}
+}
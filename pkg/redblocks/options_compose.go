@@ -1,22 +1,46 @@
 package redblocks
 
 type ComposeOption struct {
-	Key string
+	Key     string // Used instead of the type name of the set
+	Prefix  string
+	Version string
 }
 
 func ComposeOptionsToComposeOption(opts []ComposeOption) (ComposeOption, error) {
 	opt := ComposeOption{}
 	for _, o := range opts {
-		if o.Key == "" {
+		if o.Key != "" {
 			opt.Key = o.Key
 		}
+		if o.Prefix != "" {
+			opt.Prefix = o.Prefix
+		}
+		if o.Version != "" {
+			opt.Version = o.Version
+		}
 	}
 
 	return opt, nil
 }
 
+// WithKey gives a stable name to the set. Without it the type name is used, which changes when the type is renamed or moved.
 func WithKey(key string) ComposeOption {
 	return ComposeOption{
 		Key: key,
 	}
 }
+
+// WithPrefix namespaces keys, e.g. per service or environment. Operators are namespaced when all of their children share the prefix.
+func WithPrefix(prefix string) ComposeOption {
+	return ComposeOption{
+		Prefix: prefix,
+	}
+}
+
+// WithVersion tags keys with a schema version. Bumping it changes the key of the set and of all sets derived from it,
+// so old data is never read and just expires.
+func WithVersion(version string) ComposeOption {
+	return ComposeOption{
+		Version: version,
+	}
+}
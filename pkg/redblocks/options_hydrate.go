@@ -0,0 +1,55 @@
+package redblocks
+
+type MissingPolicy int
+
+const (
+	// RefillMissing loads more IDs to fill the page when some IDs no longer resolve
+	RefillMissing MissingPolicy = iota
+	// DropMissing drops IDs which no longer resolve, so a page may be shorter than requested
+	DropMissing
+)
+
+func (p MissingPolicy) String() string {
+	switch p {
+	case RefillMissing:
+		return "RefillMissing"
+	case DropMissing:
+		return "DropMissing"
+	default:
+		return ""
+	}
+}
+
+type HydrateOption struct {
+	MissingPolicy MissingPolicy
+	MaxRefills    int // Max number of extra loads per page with RefillMissing
+}
+
+func HydrateOptionsToHydrateOption(opts []HydrateOption) (HydrateOption, error) {
+	opt := HydrateOption{
+		MissingPolicy: RefillMissing,
+		MaxRefills:    3,
+	}
+	for _, o := range opts {
+		if opt.MissingPolicy == RefillMissing && o.MissingPolicy != RefillMissing {
+			opt.MissingPolicy = o.MissingPolicy
+		}
+		if o.MaxRefills != 0 {
+			opt.MaxRefills = o.MaxRefills
+		}
+	}
+
+	return opt, nil
+}
+
+func WithMissingPolicy(policy MissingPolicy) HydrateOption {
+	return HydrateOption{
+		MissingPolicy: policy,
+	}
+}
+
+func WithMaxRefills(maxRefills int) HydrateOption {
+	return HydrateOption{
+		MaxRefills: maxRefills,
+	}
+}
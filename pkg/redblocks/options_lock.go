@@ -0,0 +1,70 @@
+package redblocks
+
+import (
+	"time"
+)
+
+type LockWaitPolicy int
+
+const (
+	// WaitForResult waits until the lock holder finishes refreshing
+	WaitForResult LockWaitPolicy = iota
+	// ServeStale returns immediately when the current data still exists, and waits otherwise
+	ServeStale
+)
+
+func (p LockWaitPolicy) String() string {
+	switch p {
+	case WaitForResult:
+		return "WaitForResult"
+	case ServeStale:
+		return "ServeStale"
+	default:
+		return ""
+	}
+}
+
+type LockOption struct {
+	Timeout       time.Duration // Lock is released after Timeout even if the holder does not unlock. Waiters give up after Timeout too.
+	WaitPolicy    LockWaitPolicy
+	RetryInterval time.Duration
+}
+
+func LockOptionsToLockOption(opts []LockOption) (LockOption, error) {
+	opt := LockOption{
+		Timeout:       time.Second * 10,
+		WaitPolicy:    WaitForResult,
+		RetryInterval: time.Millisecond * 50,
+	}
+	for _, o := range opts {
+		if o.Timeout != 0 {
+			opt.Timeout = o.Timeout
+		}
+		if opt.WaitPolicy == WaitForResult && o.WaitPolicy != WaitForResult {
+			opt.WaitPolicy = o.WaitPolicy
+		}
+		if o.RetryInterval != 0 {
+			opt.RetryInterval = o.RetryInterval
+		}
+	}
+
+	return opt, nil
+}
+
+func WithLockTimeout(timeout time.Duration) LockOption {
+	return LockOption{
+		Timeout: timeout,
+	}
+}
+
+func WithLockWaitPolicy(policy LockWaitPolicy) LockOption {
+	return LockOption{
+		WaitPolicy: policy,
+	}
+}
+
+func WithLockRetryInterval(interval time.Duration) LockOption {
+	return LockOption{
+		RetryInterval: interval,
+	}
+}
@@ -3,3 +3,72 @@ package redblocks
 type Operator struct {
 	Weight []float64
 }
+
+type ChildFailurePolicy int
+
+const (
+	// FailOnChildError fails the update when any child fails to warm up
+	FailOnChildError ChildFailurePolicy = iota
+	// SkipFailedChildren goes on with what is stored at keys of failed children, which is stale or empty.
+	// Only union supports it, since a skipped subtrahend would leak members. The update still fails when all children fail.
+	SkipFailedChildren
+)
+
+func (p ChildFailurePolicy) String() string {
+	switch p {
+	case FailOnChildError:
+		return "FailOnChildError"
+	case SkipFailedChildren:
+		return "SkipFailedChildren"
+	default:
+		return ""
+	}
+}
+
+type OperatorOption struct {
+	Concurrency   int // Max number of children warmed up at once
+	FailurePolicy ChildFailurePolicy
+	OnChildError  func(key string, err error) // Called with errors of children skipped by SkipFailedChildren
+}
+
+func OperatorOptionsToOperatorOption(opts []OperatorOption) (OperatorOption, error) {
+	opt := OperatorOption{
+		Concurrency:   8,
+		FailurePolicy: FailOnChildError,
+	}
+	for _, o := range opts {
+		if o.Concurrency != 0 {
+			opt.Concurrency = o.Concurrency
+		}
+		if opt.FailurePolicy == FailOnChildError && o.FailurePolicy != FailOnChildError {
+			opt.FailurePolicy = o.FailurePolicy
+		}
+		if o.OnChildError != nil {
+			opt.OnChildError = o.OnChildError
+		}
+	}
+	if opt.Concurrency < 1 {
+		opt.Concurrency = 1
+	}
+
+	return opt, nil
+}
+
+// WithChildConcurrency limits the number of children warmed up at once. 1 warms them up one by one.
+func WithChildConcurrency(concurrency int) OperatorOption {
+	return OperatorOption{
+		Concurrency: concurrency,
+	}
+}
+
+func WithChildFailurePolicy(policy ChildFailurePolicy) OperatorOption {
+	return OperatorOption{
+		FailurePolicy: policy,
+	}
+}
+
+func WithOnChildError(onError func(key string, err error)) OperatorOption {
+	return OperatorOption{
+		OnChildError: onError,
+	}
+}
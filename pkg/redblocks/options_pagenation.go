@@ -4,6 +4,15 @@ type PagenationOption struct {
 	Head  int64
 	Tail  int64
 	Order Order
+
+	// Score range. Head and Tail are ignored when ByScore is true.
+	ByScore bool
+	Min     ScoreBound
+	Max     ScoreBound
+	Offset  int64
+	Count   int64 // Negative value means no limit
+	// Limit is true when Offset and Count are given by WithLimit, so zero Count means an empty page instead of unset.
+	Limit bool
 }
 
 func PagenationOptionsToPagenationOption(opts []PagenationOption) (PagenationOption, error) {
@@ -11,6 +20,9 @@ func PagenationOptionsToPagenationOption(opts []PagenationOption) (PagenationOpt
 		Head:  0,
 		Tail:  -1,
 		Order: Asc,
+		Min:   NegativeInf,
+		Max:   PositiveInf,
+		Count: -1,
 	}
 	for _, o := range opts {
 		if o.Head != 0 {
@@ -22,6 +34,22 @@ func PagenationOptionsToPagenationOption(opts []PagenationOption) (PagenationOpt
 		if opt.Order == Asc && o.Order != Asc {
 			opt.Order = o.Order
 		}
+		if o.ByScore {
+			opt.ByScore = true
+			opt.Min = o.Min
+			opt.Max = o.Max
+		}
+		if o.Limit {
+			opt.Limit = true
+			opt.Offset = o.Offset
+			opt.Count = o.Count
+		}
+		if o.Offset != 0 {
+			opt.Offset = o.Offset
+		}
+		if o.Count != 0 {
+			opt.Count = o.Count
+		}
 	}
 
 	return opt, nil
@@ -39,3 +67,23 @@ func WithOrder(order Order) PagenationOption {
 		Order: order,
 	}
 }
+
+// WithScoreRange selects members whose score is between min and max, like ZRANGEBYSCORE.
+// min and max keep their meaning with Desc order.
+func WithScoreRange(min ScoreBound, max ScoreBound) PagenationOption {
+	return PagenationOption{
+		ByScore: true,
+		Min:     min,
+		Max:     max,
+	}
+}
+
+// WithLimit is LIMIT offset count of ZRANGEBYSCORE. It is used with WithScoreRange.
+// Zero count returns no members and negative count means no limit, same as Redis.
+func WithLimit(offset int64, count int64) PagenationOption {
+	return PagenationOption{
+		Limit:  true,
+		Offset: offset,
+		Count:  count,
+	}
+}
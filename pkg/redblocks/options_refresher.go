@@ -0,0 +1,91 @@
+package redblocks
+
+import (
+	"time"
+)
+
+type RefresherOption struct {
+	Workers       int
+	Interval      time.Duration // How often Run looks for sets to refresh. Negative is clamped to a millisecond.
+	Lead          time.Duration // Sets are refreshed Lead before TTL goes below NotAvailableTTL
+	Jitter        time.Duration // Random duration up to Jitter is subtracted from each schedule, so pods do not refresh at the same time
+	RetryInterval time.Duration // Wait after a failed refresh
+	Now           func() time.Time
+}
+
+func RefresherOptionsToRefresherOption(opts []RefresherOption) (RefresherOption, error) {
+	opt := RefresherOption{
+		Workers:       4,
+		Interval:      time.Second,
+		Lead:          time.Second * 2,
+		Jitter:        time.Second,
+		RetryInterval: time.Second * 5,
+		Now:           time.Now,
+	}
+	for _, o := range opts {
+		if o.Workers != 0 {
+			opt.Workers = o.Workers
+		}
+		if o.Interval != 0 {
+			opt.Interval = o.Interval
+		}
+		if o.Lead != 0 {
+			opt.Lead = o.Lead
+		}
+		if o.Jitter != 0 {
+			opt.Jitter = o.Jitter
+		}
+		if o.RetryInterval != 0 {
+			opt.RetryInterval = o.RetryInterval
+		}
+		if o.Now != nil {
+			opt.Now = o.Now
+		}
+	}
+	if opt.Workers < 1 {
+		opt.Workers = 1
+	}
+	// time.NewTicker panics on a non positive interval
+	if opt.Interval < 0 {
+		opt.Interval = time.Millisecond
+	}
+
+	return opt, nil
+}
+
+func WithRefreshWorkers(workers int) RefresherOption {
+	return RefresherOption{
+		Workers: workers,
+	}
+}
+
+func WithRefreshInterval(interval time.Duration) RefresherOption {
+	return RefresherOption{
+		Interval: interval,
+	}
+}
+
+func WithRefreshLead(lead time.Duration) RefresherOption {
+	return RefresherOption{
+		Lead: lead,
+	}
+}
+
+// WithRefreshJitter sets max jitter. Negative value disables jitter.
+func WithRefreshJitter(jitter time.Duration) RefresherOption {
+	return RefresherOption{
+		Jitter: jitter,
+	}
+}
+
+func WithRefreshRetryInterval(interval time.Duration) RefresherOption {
+	return RefresherOption{
+		RetryInterval: interval,
+	}
+}
+
+func WithRefreshClock(now func() time.Time) RefresherOption {
+	return RefresherOption{
+		Now: now,
+	}
+}
@@ -0,0 +1,24 @@
+package redblocks
+
+type StoreOption struct {
+	ChunkSize int // Number of members sent by one ZADD
+}
+
+func StoreOptionsToStoreOption(opts []StoreOption) (StoreOption, error) {
+	opt := StoreOption{
+		ChunkSize: 1000,
+	}
+	for _, o := range opts {
+		if o.ChunkSize > 0 {
+			opt.ChunkSize = o.ChunkSize
+		}
+	}
+
+	return opt, nil
+}
+
+func WithChunkSize(size int) StoreOption {
+	return StoreOption{
+		ChunkSize: size,
+	}
+}
@@ -0,0 +1,23 @@
+package redblocks
+
+type WarmupOption struct {
+	Revalidator *Revalidator
+}
+
+func WarmupOptionsToWarmupOption(opts []WarmupOption) (WarmupOption, error) {
+	opt := WarmupOption{}
+	for _, o := range opts {
+		if o.Revalidator != nil {
+			opt.Revalidator = o.Revalidator
+		}
+	}
+
+	return opt, nil
+}
+
+// WithStaleWhileRevalidate makes Warmup return immediately while the key still exists, and update it by r in background.
+func WithStaleWhileRevalidate(r *Revalidator) WarmupOption {
+	return WarmupOption{
+		Revalidator: r,
+	}
+}
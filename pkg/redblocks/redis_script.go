@@ -0,0 +1,171 @@
+package redblocks
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
+)
+
+// subtractionScript is used instead of ZDIFFSTORE, which is only available since Redis 6.2.
+// KEYS[1] is dst, KEYS[2] is the set to subtract from and the rest are subtracted. ARGV[1] is expire in milliseconds.
+const subtractionScript = `
+local members = redis.call('ZRANGE', KEYS[2], 0, -1, 'WITHSCORES')
+redis.call('DEL', KEYS[1])
+for i = 1, #members, 2 do
+	local found = false
+	for j = 3, #KEYS do
+		if redis.call('ZSCORE', KEYS[j], members[i]) then
+			found = true
+			break
+		end
+	end
+	if not found then
+		redis.call('ZADD', KEYS[1], members[i + 1], members[i])
+	end
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return redis.call('ZCARD', KEYS[1])
+`
+
+// topNScript copies KEYS[2] into KEYS[1] and removes ranks from ARGV[1] to ARGV[2]. ARGV[3] is expire in milliseconds.
+const topNScript = `
+redis.call('ZUNIONSTORE', KEYS[1], 1, KEYS[2])
+redis.call('ZREMRANGEBYRANK', KEYS[1], ARGV[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return redis.call('ZCARD', KEYS[1])
+`
+
+// filterByScoreScript copies KEYS[2] into KEYS[1] and removes scores up to ARGV[1] and from ARGV[2]. ARGV[3] is expire in milliseconds.
+const filterByScoreScript = `
+redis.call('ZUNIONSTORE', KEYS[1], 1, KEYS[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], ARGV[2], '+inf')
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return redis.call('ZCARD', KEYS[1])
+`
+
+// topNRange returns ranks to remove to keep the first n members in order
+func topNRange(n int64, order Order) (int64, int64, error) {
+	if n < 0 {
+		return 0, 0, fail.Wrap(fail.New("n must not be negative"), fail.WithParam("n", n))
+	}
+	switch order {
+	case Asc:
+		return n, -1, nil
+	case Desc:
+		return 0, -n - 1, nil
+	default:
+		return 0, 0, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+}
+
+// outside returns bounds of scores below min and above max, which are removed by filterByScoreScript
+func outside(min ScoreBound, max ScoreBound) (ScoreBound, ScoreBound) {
+	return ScoreBound{Score: min.Score, Exclusive: !min.Exclusive}, ScoreBound{Score: max.Score, Exclusive: !max.Exclusive}
+}
+
+// applyDeltaScript applies a delta to KEYS[1] when it exists and the watermark at KEYS[2] is ARGV[1], and sets the watermark to ARGV[2].
+// ARGV[3] is expire in milliseconds, followed by removed members, added members and incremented members, each prefixed by its count.
+// Arguments are checked before anything is written, so a delta is applied entirely or not at all.
+const applyDeltaScript = `
+local ttl = tonumber(ARGV[3])
+if not ttl or ttl < 1 then
+	return redis.error_reply('ERR invalid expire')
+end
+local counts = {}
+local i = 4
+for k = 1, 3 do
+	local n = tonumber(ARGV[i])
+	if not n or n < 0 then
+		return redis.error_reply('ERR invalid count')
+	end
+	counts[k] = n
+	i = i + 1
+	if k == 1 then
+		i = i + n
+	else
+		for j = 1, n do
+			local score = tonumber(ARGV[i])
+			if not score or score ~= score then
+				return redis.error_reply('ERR invalid score')
+			end
+			i = i + 2
+		end
+	end
+end
+if i - 1 ~= #ARGV then
+	return redis.error_reply('ERR wrong number of arguments')
+end
+if (redis.call('GET', KEYS[2]) or '') ~= ARGV[1] then
+	return 0
+end
+-- A delta on a missing key would leave a set of only the delta
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+i = 5
+for j = 1, counts[1] do
+	redis.call('ZREM', KEYS[1], ARGV[i])
+	i = i + 1
+end
+i = i + 1
+for j = 1, counts[2] do
+	redis.call('ZADD', KEYS[1], ARGV[i], ARGV[i + 1])
+	i = i + 2
+end
+i = i + 1
+for j = 1, counts[3] do
+	redis.call('ZINCRBY', KEYS[1], ARGV[i], ARGV[i + 1])
+	i = i + 2
+end
+if ARGV[2] == '' then
+	redis.call('DEL', KEYS[2])
+else
+	redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`
+
+// applyDeltaArgs returns ARGV of applyDeltaScript
+func applyDeltaArgs(from string, delta Delta, expire time.Duration) ([]interface{}, error) {
+	if err := validateDelta(delta, expire); err != nil {
+		return []interface{}{}, fail.Wrap(err)
+	}
+	args := []interface{}{from, delta.Watermark, expire.Milliseconds()}
+	args = append(args, len(delta.Removed))
+	for _, id := range delta.Removed {
+		args = append(args, string(id))
+	}
+	args = append(args, len(delta.Added))
+	for _, v := range delta.Added {
+		args = append(args, v.Score, string(v.ID))
+	}
+	args = append(args, len(delta.Incremented))
+	for _, v := range delta.Incremented {
+		args = append(args, v.Score, string(v.ID))
+	}
+	return args, nil
+}
+
+// addDependentScript adds ARGV[1] to KEYS[1] and extends its expire to ARGV[2] milliseconds if it is shorter.
+const addDependentScript = `
+redis.call('SADD', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 1
+`
+
+// unlockScript deletes KEYS[1] only when it is still held by ARGV[1]
+const unlockScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+func isUnknownCommand(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "ERR unknown command")
+}
@@ -2,45 +2,126 @@ package redblocks
 
 import (
 	"context"
-	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
-	"github.com/srvc/fail"
+	"github.com/rerost/redblocks-go/internal/fail"
 )
 
-func NewRedisStore(pool *redis.Pool) Store {
+func NewRedisStore(pool *redis.Pool, opts ...StoreOption) Store {
+	opt, _ := StoreOptionsToStoreOption(opts)
 	return redisStoreImp{
 		pool: pool,
+		opt:  opt,
 	}
 }
 
 type redisStoreImp struct {
 	pool *redis.Pool
+	opt  StoreOption
 }
 
+// conn gets a connection from the pool. It must be closed by the caller.
+func (s redisStoreImp) conn(ctx context.Context) (redis.Conn, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, storeErr(ctx, err)
+	}
+	return conn, nil
+}
+
+// do sends cmd and waits for the reply until ctx is done.
+func do(ctx context.Context, conn redis.Conn, cmd string, args ...interface{}) (interface{}, error) {
+	reply, err := redis.DoContext(conn, ctx, cmd, args...)
+	return reply, storeErr(ctx, err)
+}
+
+// flush sends commands queued by conn.Send and receives their replies. An error reply is returned as err.
+func flush(ctx context.Context, conn redis.Conn) ([]interface{}, error) {
+	replies, err := redis.Values(do(ctx, conn, ""))
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(redis.Error); ok {
+			return replies, err
+		}
+	}
+	return replies, nil
+}
+
+// Save replaces key with idsWithScore. See SaveIterator.
 func (s redisStoreImp) Save(ctx context.Context, key string, idsWithScore []IDWithScore, expire time.Duration) error {
-	conn := s.pool.Get()
+	return fail.Wrap(s.SaveIterator(ctx, key, SliceIterator(idsWithScore), expire))
+}
+
+// SaveIterator replaces key with members of it. Members are written into a temporary key which is renamed to key at the end,
+// so readers never see a half written set and members which are not in it are removed.
+// Members are sent ChunkSize at a time, so memory usage does not grow with the size of the set.
+func (s redisStoreImp) SaveIterator(ctx context.Context, key string, it IDWithScoreIterator, expire time.Duration) error {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	tmp, err := tempKey(key)
+	if err != nil {
+		return fail.Wrap(err)
+	}
 
-	for _, idWithScore := range idsWithScore {
-		err := conn.Send("ZADD", key, idWithScore.Score, idWithScore.ID)
+	written := 0
+	chunk := make([]IDWithScore, 0, s.opt.ChunkSize)
+	for {
+		chunk, err = nextChunk(ctx, it, chunk, s.opt.ChunkSize)
 		if err != nil {
+			conn.Do("DEL", tmp)
+			return fail.Wrap(canceled(ctx, err))
+		}
+		if len(chunk) == 0 {
+			break
+		}
+
+		args := make([]interface{}, 0, len(chunk)*2+1)
+		args = append(args, tmp)
+		for _, idWithScore := range chunk {
+			args = append(args, idWithScore.Score, idWithScore.ID)
+		}
+		conn.Send("ZADD", args...)
+		// Temporary key expires even if rename is not reached
+		if written == 0 {
+			conn.Send("PEXPIRE", tmp, expire.Milliseconds())
+		}
+		if _, err := flush(ctx, conn); err != nil {
+			conn.Do("DEL", tmp)
 			return fail.Wrap(err)
 		}
-	}
+		written += len(chunk)
 
-	conn.Send("EXPIRE", key, expire.Seconds())
+		if len(chunk) < s.opt.ChunkSize {
+			break
+		}
+	}
 
-	if err := conn.Flush(); err != nil {
+	if written == 0 {
+		_, err := do(ctx, conn, "DEL", key)
 		return fail.Wrap(err)
 	}
 
-	return nil
+	_, err = do(ctx, conn, "RENAME", tmp, key)
+	if err != nil {
+		conn.Do("DEL", tmp)
+	}
+	return fail.Wrap(err)
 }
 
 func (s redisStoreImp) GetIDs(ctx context.Context, key string, head int64, tail int64, order Order) ([]ID, error) {
-	conn := s.pool.Get()
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return []ID{}, fail.Wrap(err)
+	}
+	defer conn.Close()
 
 	var cmd string
 	switch order {
@@ -49,10 +130,10 @@ func (s redisStoreImp) GetIDs(ctx context.Context, key string, head int64, tail
 	case Desc:
 		cmd = "ZREVRANGE"
 	default:
-		panic(fmt.Sprintf("Undefined order passed: %v", order.String()))
+		return []ID{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
 	}
 
-	IDs, err := redis.Strings(conn.Do(cmd, key, head, tail))
+	IDs, err := redis.Strings(do(ctx, conn, cmd, key, head, tail))
 	if err != nil {
 		return []ID{}, fail.Wrap(err)
 	}
@@ -65,7 +146,11 @@ func (s redisStoreImp) GetIDs(ctx context.Context, key string, head int64, tail
 }
 
 func (s redisStoreImp) GetIDsWithScore(ctx context.Context, key string, head int64, tail int64, order Order) ([]IDWithScore, error) {
-	conn := s.pool.Get()
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return []IDWithScore{}, fail.Wrap(err)
+	}
+	defer conn.Close()
 
 	var cmd string
 	switch order {
@@ -74,10 +159,85 @@ func (s redisStoreImp) GetIDsWithScore(ctx context.Context, key string, head int
 	case Desc:
 		cmd = "ZREVRANGE"
 	default:
-		panic(fmt.Sprintf("Undefined order passed: %v", order.String()))
+		return []IDWithScore{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+
+	results, err := redis.Strings(do(ctx, conn, cmd, key, head, tail, "WITHSCORES"))
+	if err != nil {
+		return []IDWithScore{}, fail.Wrap(err)
+	}
+
+	idsWithScore := make([]IDWithScore, len(results)/2, len(results)/2)
+	for i, result := range results {
+		if i%2 == 0 {
+			idsWithScore[i/2].ID = ID(result)
+		} else {
+			score, err := strconv.ParseFloat(result, 64)
+			if err != nil {
+				return idsWithScore, fail.Wrap(err)
+			}
+			idsWithScore[(i-1)/2].Score = score
+		}
+	}
+
+	return idsWithScore, nil
+}
+
+func (s redisStoreImp) GetIDsByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]ID, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return []ID{}, fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	var cmd string
+	var args []interface{}
+	switch order {
+	case Asc:
+		cmd = "ZRANGEBYSCORE"
+		args = []interface{}{key, min.String(), max.String()}
+	case Desc:
+		cmd = "ZREVRANGEBYSCORE"
+		args = []interface{}{key, max.String(), min.String()}
+	default:
+		return []ID{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+	args = append(args, "LIMIT", offset, count)
+
+	IDs, err := redis.Strings(do(ctx, conn, cmd, args...))
+	if err != nil {
+		return []ID{}, fail.Wrap(err)
+	}
+
+	ids := make([]ID, len(IDs), len(IDs))
+	for i, id := range IDs {
+		ids[i] = ID(id)
+	}
+	return ids, nil
+}
+
+func (s redisStoreImp) GetIDsWithScoreByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]IDWithScore, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return []IDWithScore{}, fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	var cmd string
+	var args []interface{}
+	switch order {
+	case Asc:
+		cmd = "ZRANGEBYSCORE"
+		args = []interface{}{key, min.String(), max.String()}
+	case Desc:
+		cmd = "ZREVRANGEBYSCORE"
+		args = []interface{}{key, max.String(), min.String()}
+	default:
+		return []IDWithScore{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
 	}
+	args = append(args, "WITHSCORES", "LIMIT", offset, count)
 
-	results, err := redis.Strings(conn.Do(cmd, key, head, tail, "WITHSCORES"))
+	results, err := redis.Strings(do(ctx, conn, cmd, args...))
 	if err != nil {
 		return []IDWithScore{}, fail.Wrap(err)
 	}
@@ -99,9 +259,13 @@ func (s redisStoreImp) GetIDsWithScore(ctx context.Context, key string, head int
 }
 
 func (s redisStoreImp) Exists(ctx context.Context, key string) (bool, error) {
-	conn := s.pool.Get()
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	defer conn.Close()
 
-	result, err := redis.Bool(conn.Do("EXISTS", key))
+	result, err := redis.Bool(do(ctx, conn, "EXISTS", key))
 	if err != nil {
 		return false, fail.Wrap(err)
 	}
@@ -110,9 +274,13 @@ func (s redisStoreImp) Exists(ctx context.Context, key string) (bool, error) {
 }
 
 func (s redisStoreImp) TTL(ctx context.Context, key string) (time.Duration, error) {
-	conn := s.pool.Get()
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return 0, fail.Wrap(err)
+	}
+	defer conn.Close()
 
-	result, err := redis.Int64(conn.Do("TTL", key))
+	result, err := redis.Int64(do(ctx, conn, "TTL", key))
 
 	if err != nil {
 		return 0, fail.Wrap(err)
@@ -121,19 +289,23 @@ func (s redisStoreImp) TTL(ctx context.Context, key string) (time.Duration, erro
 	// See https://redis.io/commands/TTL
 	if result < 0 {
 		if result == -2 {
-			return 0, fail.Wrap(fail.New("Not found"), fail.WithParam("key", key))
+			return 0, fail.Wrap(ErrKeyNotFound, fail.WithParam("key", key))
 		}
 		if result == -1 {
-			return 0, fail.Wrap(fail.New("Not configured expire"), fail.WithParam("key", key))
+			return 0, fail.Wrap(ErrNoExpiry, fail.WithParam("key", key))
 		}
-		panic(fail.Wrap(fail.New("Returned unexpected ttl"), fail.WithParam("key", key), fail.WithParam("ttl", result)))
+		return 0, fail.Wrap(fail.New("Returned unexpected ttl"), fail.WithParam("key", key), fail.WithParam("ttl", result))
 	}
 
-	return time.Duration(result * int64(time.Millisecond)), nil
+	return time.Duration(result) * time.Second, nil
 }
 
 func (s redisStoreImp) Interstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error {
-	conn := s.pool.Get()
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
 	args := []interface{}{}
 	args = append(args, dst)
 	args = append(args, len(keys))
@@ -148,13 +320,17 @@ func (s redisStoreImp) Interstore(ctx context.Context, dst string, expire time.D
 	args = append(args, aggregate.String())
 
 	conn.Send("ZINTERSTORE", args...)
-	conn.Send("EXPIRE", dst, expire.Seconds())
-	err := conn.Flush()
+	conn.Send("PEXPIRE", dst, expire.Milliseconds())
+	_, err = flush(ctx, conn)
 	return fail.Wrap(err)
 }
 
 func (s redisStoreImp) Unionstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error {
-	conn := s.pool.Get()
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
 	args := []interface{}{}
 	args = append(args, dst)
 	args = append(args, len(keys))
@@ -169,29 +345,279 @@ func (s redisStoreImp) Unionstore(ctx context.Context, dst string, expire time.D
 	args = append(args, aggregate.String())
 
 	conn.Send("ZUNIONSTORE", args...)
-	conn.Send("EXPIRE", dst, expire.Seconds())
-	err := conn.Flush()
+	conn.Send("PEXPIRE", dst, expire.Milliseconds())
+	_, err = flush(ctx, conn)
+	return fail.Wrap(err)
+}
+
+// Subtraction stores key - (subtrahends[0] | subtrahends[1] | ...) into dst. Scores of key are kept.
+// ZDIFFSTORE is used if the server supports it, otherwise a Lua script does the same thing.
+func (s redisStoreImp) Subtraction(ctx context.Context, dst string, expire time.Duration, key string, subtrahends ...string) error {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	args := []interface{}{}
+	args = append(args, dst)
+	args = append(args, len(subtrahends)+1)
+	args = append(args, key)
+	for _, k := range subtrahends {
+		args = append(args, k)
+	}
+
+	_, err = do(ctx, conn, "ZDIFFSTORE", args...)
+	if isUnknownCommand(err) {
+		keysAndArgs := []interface{}{dst, key}
+		for _, k := range subtrahends {
+			keysAndArgs = append(keysAndArgs, k)
+		}
+		keysAndArgs = append(keysAndArgs, expire.Milliseconds())
+
+		_, err = redis.NewScript(len(subtrahends)+2, subtractionScript).DoContext(ctx, conn, keysAndArgs...)
+		return fail.Wrap(storeErr(ctx, err))
+	}
+	if err != nil {
+		return fail.Wrap(err)
+	}
+
+	_, err = do(ctx, conn, "PEXPIRE", dst, expire.Milliseconds())
+	return fail.Wrap(err)
+}
+
+func (s redisStoreImp) TopN(ctx context.Context, dst string, expire time.Duration, n int64, order Order, key string) error {
+	start, stop, err := topNRange(n, order)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	_, err = redis.NewScript(2, topNScript).DoContext(ctx, conn, dst, key, start, stop, expire.Milliseconds())
+	return fail.Wrap(storeErr(ctx, err))
+}
+
+func (s redisStoreImp) FilterByScore(ctx context.Context, dst string, expire time.Duration, min ScoreBound, max ScoreBound, key string) error {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	below, above := outside(min, max)
+	_, err = redis.NewScript(2, filterByScoreScript).DoContext(ctx, conn, dst, key, below.String(), above.String(), expire.Milliseconds())
+	return fail.Wrap(storeErr(ctx, err))
+}
+
+func (s redisStoreImp) ApplyDelta(ctx context.Context, key string, from string, delta Delta, expire time.Duration) (bool, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	args, err := applyDeltaArgs(from, delta, expire)
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	keysAndArgs := append([]interface{}{key, WatermarkKey(key)}, args...)
+	applied, err := redis.Int64(redis.NewScript(2, applyDeltaScript).DoContext(ctx, conn, keysAndArgs...))
+	if err != nil {
+		return false, fail.Wrap(storeErr(ctx, err))
+	}
+	return applied == 1, nil
+}
+
+func (s redisStoreImp) Watermark(ctx context.Context, key string) (string, bool, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return "", false, fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	watermark, err := redis.String(do(ctx, conn, "GET", WatermarkKey(key)))
+	if err == redis.ErrNil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fail.Wrap(err)
+	}
+	return watermark, true, nil
+}
+
+func (s redisStoreImp) SetWatermark(ctx context.Context, key string, watermark string, expire time.Duration) error {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	if watermark == "" {
+		_, err = do(ctx, conn, "DEL", WatermarkKey(key))
+		return fail.Wrap(err)
+	}
+	if err := validateWatermarkExpire(expire); err != nil {
+		return fail.Wrap(err)
+	}
+	_, err = do(ctx, conn, "SET", WatermarkKey(key), watermark, "PX", expire.Milliseconds())
 	return fail.Wrap(err)
 }
 
-// WARING: This function is experimental.
-// Because
-// - Slow
-// - set2's score needs to be much larger than set1' sscore
-// - set2's score needs to be a negative value
-func (s redisStoreImp) Subtraction(ctx context.Context, dst string, expire time.Duration, key1 string, key2 string) error {
-	conn := s.pool.Get()
+func (s redisStoreImp) Delete(ctx context.Context, keys ...string) error {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
 
-	conn.Send("MULTI")
-	conn.Send("ZUNIONSTORE", dst, 2, key1, key2, "WEIGHTS", 1, 1, "AGGREGATE", "SUM")
-	conn.Send("ZREMRANGEBYSCORE", dst, "-inf", "(0")
-	conn.Send("EXPIRE", dst, expire.Seconds())
-	_, err := conn.Do("EXEC")
+	args := make([]interface{}, len(keys), len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	_, err = do(ctx, conn, "DEL", args...)
 	return fail.Wrap(err)
 }
 
+func (s redisStoreImp) AddDependent(ctx context.Context, key string, dependent string, expire time.Duration) error {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	_, err = redis.NewScript(1, addDependentScript).DoContext(ctx, conn, DependentsKey(key), dependent, expire.Milliseconds())
+	return fail.Wrap(storeErr(ctx, err))
+}
+
+func (s redisStoreImp) Dependents(ctx context.Context, key string) ([]string, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return []string{}, fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	dependents, err := redis.Strings(do(ctx, conn, "SMEMBERS", DependentsKey(key)))
+	if err != nil {
+		return []string{}, fail.Wrap(err)
+	}
+	return dependents, nil
+}
+
 func (s redisStoreImp) Count(ctx context.Context, key string) (int64, error) {
-	conn := s.pool.Get()
-	count, err := redis.Int64(conn.Do("ZCARD", key))
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return 0, fail.Wrap(err)
+	}
+	defer conn.Close()
+	count, err := redis.Int64(do(ctx, conn, "ZCARD", key))
 	return count, fail.Wrap(err)
 }
+
+func (s redisStoreImp) CountByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound) (int64, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return 0, fail.Wrap(err)
+	}
+	defer conn.Close()
+	count, err := redis.Int64(do(ctx, conn, "ZCOUNT", key, min.String(), max.String()))
+	return count, fail.Wrap(err)
+}
+
+// Scores uses ZMSCORE if the server supports it, otherwise ZSCORE for each id.
+func (s redisStoreImp) Scores(ctx context.Context, key string, ids ...ID) (map[ID]float64, error) {
+	scores := map[ID]float64{}
+	if len(ids) == 0 {
+		return scores, nil
+	}
+
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return scores, fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, key)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	replies, err := redis.Values(do(ctx, conn, "ZMSCORE", args...))
+	if isUnknownCommand(err) {
+		for _, id := range ids {
+			conn.Send("ZSCORE", key, id)
+		}
+		replies, err = flush(ctx, conn)
+	}
+	if err != nil {
+		return scores, fail.Wrap(err)
+	}
+
+	for i, reply := range replies {
+		// Not a member
+		if reply == nil {
+			continue
+		}
+		score, err := redis.Float64(reply, nil)
+		if err != nil {
+			return scores, fail.Wrap(err)
+		}
+		scores[ids[i]] = score
+	}
+	return scores, nil
+}
+
+func (s redisStoreImp) Rank(ctx context.Context, key string, id ID, order Order) (int64, bool, error) {
+	var cmd string
+	switch order {
+	case Asc:
+		cmd = "ZRANK"
+	case Desc:
+		cmd = "ZREVRANK"
+	default:
+		return 0, false, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return 0, false, fail.Wrap(err)
+	}
+	defer conn.Close()
+
+	rank, err := redis.Int64(do(ctx, conn, cmd, key, id))
+	if err == redis.ErrNil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fail.Wrap(err)
+	}
+	return rank, true, nil
+}
+
+func (s redisStoreImp) Lock(ctx context.Context, key string, token string, timeout time.Duration) (bool, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	defer conn.Close()
+	reply, err := do(ctx, conn, "SET", key, token, "NX", "PX", int64(timeout/time.Millisecond))
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	return reply != nil, nil
+}
+
+func (s redisStoreImp) Unlock(ctx context.Context, key string, token string) error {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer conn.Close()
+	_, err = redis.NewScript(1, unlockScript).DoContext(ctx, conn, key, token)
+	return fail.Wrap(storeErr(ctx, err))
+}
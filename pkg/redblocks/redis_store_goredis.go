@@ -2,37 +2,93 @@ package redblocks
 
 import (
 	"context"
-	"fmt"
+	"strconv"
 	"time"
 
-	go_redis "github.com/go-redis/redis"
-	"github.com/srvc/fail"
+	go_redis "github.com/go-redis/redis/v8"
+	"github.com/rerost/redblocks-go/internal/fail"
 )
 
 type RedisClientFunc func(context context.Context) *go_redis.Client
 
-func NewGoredisStore(redisClientFunc RedisClientFunc) Store {
+func NewGoredisStore(redisClientFunc RedisClientFunc, opts ...StoreOption) Store {
+	opt, _ := StoreOptionsToStoreOption(opts)
 	return newGoredisStoreImp{
 		redisClientFunc: redisClientFunc,
+		opt:             opt,
 	}
 }
 
 type newGoredisStoreImp struct {
 	redisClientFunc RedisClientFunc
+	opt             StoreOption
 }
 
+// Save replaces key with idsWithScore. See SaveIterator.
 func (s newGoredisStoreImp) Save(ctx context.Context, key string, idsWithScore []IDWithScore, expire time.Duration) error {
+	return fail.Wrap(s.SaveIterator(ctx, key, SliceIterator(idsWithScore), expire))
+}
+
+// SaveIterator replaces key with members of it. Members are written into a temporary key which is renamed to key at the end,
+// so readers never see a half written set and members which are not in it are removed.
+// Members are sent ChunkSize at a time, so memory usage does not grow with the size of the set.
+func (s newGoredisStoreImp) SaveIterator(ctx context.Context, key string, it IDWithScoreIterator, expire time.Duration) error {
 	redisClient := s.redisClientFunc(ctx)
-	pipe := redisClient.Pipeline()
 
-	for _, idWithScore := range idsWithScore {
-		pipe.ZAdd(key, go_redis.Z{Member: string(idWithScore.ID), Score: idWithScore.Score})
+	tmp, err := tempKey(key)
+	if err != nil {
+		return fail.Wrap(err)
 	}
 
-	pipe.Expire(key, expire)
-	_, err := pipe.Exec()
+	written := 0
+	chunk := make([]IDWithScore, 0, s.opt.ChunkSize)
+	members := make([]*go_redis.Z, 0, s.opt.ChunkSize)
+	for {
+		chunk, err = nextChunk(ctx, it, chunk, s.opt.ChunkSize)
+		if err != nil {
+			s.cleanup(ctx, tmp)
+			return fail.Wrap(canceled(ctx, err))
+		}
+		if len(chunk) == 0 {
+			break
+		}
+
+		members = members[:0]
+		for _, idWithScore := range chunk {
+			members = append(members, &go_redis.Z{Member: string(idWithScore.ID), Score: idWithScore.Score})
+		}
+		pipe := redisClient.Pipeline()
+		pipe.ZAdd(ctx, tmp, members...)
+		// Temporary key expires even if rename is not reached
+		if written == 0 {
+			pipe.PExpire(ctx, tmp, expire)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			s.cleanup(ctx, tmp)
+			return fail.Wrap(storeErr(ctx, err))
+		}
+		written += len(chunk)
 
-	return fail.Wrap(err)
+		if len(chunk) < s.opt.ChunkSize {
+			break
+		}
+	}
+
+	if written == 0 {
+		return fail.Wrap(storeErr(ctx, redisClient.Del(ctx, key).Err()))
+	}
+
+	if err := redisClient.Rename(ctx, tmp, key).Err(); err != nil {
+		s.cleanup(ctx, tmp)
+		return fail.Wrap(storeErr(ctx, err))
+	}
+	return nil
+}
+
+// cleanup deletes the temporary key even if ctx is canceled
+func (s newGoredisStoreImp) cleanup(ctx context.Context, tmp string) {
+	ctx = context.WithoutCancel(ctx)
+	s.redisClientFunc(ctx).Del(ctx, tmp)
 }
 
 func (s newGoredisStoreImp) GetIDs(ctx context.Context, key string, head int64, tail int64, order Order) ([]ID, error) {
@@ -41,15 +97,15 @@ func (s newGoredisStoreImp) GetIDs(ctx context.Context, key string, head int64,
 	var cmd *go_redis.StringSliceCmd
 	switch order {
 	case Asc:
-		cmd = redisClient.ZRange(key, head, tail)
+		cmd = redisClient.ZRange(ctx, key, head, tail)
 	case Desc:
-		cmd = redisClient.ZRevRange(key, head, tail)
+		cmd = redisClient.ZRevRange(ctx, key, head, tail)
 	default:
-		panic(fmt.Sprintf("Undefined order passed: %v", order.String()))
+		return []ID{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
 	}
 
 	if err := cmd.Err(); err != nil {
-		return []ID{}, fail.Wrap(err)
+		return []ID{}, fail.Wrap(storeErr(ctx, err))
 	}
 	IDs := cmd.Val()
 
@@ -66,15 +122,83 @@ func (s newGoredisStoreImp) GetIDsWithScore(ctx context.Context, key string, hea
 	var cmd *go_redis.ZSliceCmd
 	switch order {
 	case Asc:
-		cmd = redisClient.ZRangeWithScores(key, head, tail)
+		cmd = redisClient.ZRangeWithScores(ctx, key, head, tail)
 	case Desc:
-		cmd = redisClient.ZRevRangeWithScores(key, head, tail)
+		cmd = redisClient.ZRevRangeWithScores(ctx, key, head, tail)
 	default:
-		panic(fmt.Sprintf("Undefined order passed: %v", order.String()))
+		return []IDWithScore{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
 	}
 
 	if err := cmd.Err(); err != nil {
-		return []IDWithScore{}, fail.Wrap(err)
+		return []IDWithScore{}, fail.Wrap(storeErr(ctx, err))
+	}
+	results := cmd.Val()
+
+	idsWithScore := make([]IDWithScore, len(results), len(results))
+	for i, result := range results {
+		idsWithScore[i] = IDWithScore{
+			ID:    ID(result.Member.(string)),
+			Score: result.Score,
+		}
+	}
+
+	return idsWithScore, nil
+}
+
+func (s newGoredisStoreImp) GetIDsByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]ID, error) {
+	redisClient := s.redisClientFunc(ctx)
+
+	opt := &go_redis.ZRangeBy{
+		Min:    min.String(),
+		Max:    max.String(),
+		Offset: offset,
+		Count:  count,
+	}
+
+	var cmd *go_redis.StringSliceCmd
+	switch order {
+	case Asc:
+		cmd = redisClient.ZRangeByScore(ctx, key, opt)
+	case Desc:
+		cmd = redisClient.ZRevRangeByScore(ctx, key, opt)
+	default:
+		return []ID{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+
+	if err := cmd.Err(); err != nil {
+		return []ID{}, fail.Wrap(storeErr(ctx, err))
+	}
+	IDs := cmd.Val()
+
+	ids := make([]ID, len(IDs), len(IDs))
+	for i, id := range IDs {
+		ids[i] = ID(id)
+	}
+	return ids, nil
+}
+
+func (s newGoredisStoreImp) GetIDsWithScoreByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]IDWithScore, error) {
+	redisClient := s.redisClientFunc(ctx)
+
+	opt := &go_redis.ZRangeBy{
+		Min:    min.String(),
+		Max:    max.String(),
+		Offset: offset,
+		Count:  count,
+	}
+
+	var cmd *go_redis.ZSliceCmd
+	switch order {
+	case Asc:
+		cmd = redisClient.ZRangeByScoreWithScores(ctx, key, opt)
+	case Desc:
+		cmd = redisClient.ZRevRangeByScoreWithScores(ctx, key, opt)
+	default:
+		return []IDWithScore{}, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+
+	if err := cmd.Err(); err != nil {
+		return []IDWithScore{}, fail.Wrap(storeErr(ctx, err))
 	}
 	results := cmd.Val()
 
@@ -92,9 +216,9 @@ func (s newGoredisStoreImp) GetIDsWithScore(ctx context.Context, key string, hea
 func (s newGoredisStoreImp) Exists(ctx context.Context, key string) (bool, error) {
 	redisClient := s.redisClientFunc(ctx)
 
-	cmd := redisClient.Exists(key)
+	cmd := redisClient.Exists(ctx, key)
 	if err := cmd.Err(); err != nil {
-		return false, fail.Wrap(err)
+		return false, fail.Wrap(storeErr(ctx, err))
 	}
 
 	return cmd.Val() == 1, nil
@@ -103,20 +227,20 @@ func (s newGoredisStoreImp) Exists(ctx context.Context, key string) (bool, error
 func (s newGoredisStoreImp) TTL(ctx context.Context, key string) (time.Duration, error) {
 	redisClient := s.redisClientFunc(ctx)
 
-	cmd := redisClient.TTL(key)
+	cmd := redisClient.TTL(ctx, key)
 
 	if err := cmd.Err(); err != nil {
-		return 0, fail.Wrap(err)
+		return 0, fail.Wrap(storeErr(ctx, err))
 	}
 	result := cmd.Val()
 	if result < 0 {
-		if result == -2*time.Second {
-			return 0, fail.Wrap(fail.New("Not found"), fail.WithParam("key", key))
+		if result == -2 {
+			return 0, fail.Wrap(ErrKeyNotFound, fail.WithParam("key", key))
 		}
-		if result == -1*time.Second {
-			return 0, fail.Wrap(fail.New("Not configured expire"), fail.WithParam("key", key))
+		if result == -1 {
+			return 0, fail.Wrap(ErrNoExpiry, fail.WithParam("key", key))
 		}
-		panic(fail.Wrap(fail.New(fmt.Sprintf("Returned unexpected ttl: %v", result)), fail.WithParam("key", key), fail.WithParam("ttl", result)))
+		return 0, fail.Wrap(fail.New("Returned unexpected ttl"), fail.WithParam("key", key), fail.WithParam("ttl", result))
 	}
 
 	return result, nil
@@ -126,59 +250,241 @@ func (s newGoredisStoreImp) Interstore(ctx context.Context, dst string, expire t
 	redisClient := s.redisClientFunc(ctx)
 
 	pipe := redisClient.Pipeline()
-	zstore := go_redis.ZStore{
+	zstore := &go_redis.ZStore{
+		Keys:      keys,
 		Weights:   weights,
 		Aggregate: aggregate.String(),
 	}
-	pipe.ZInterStore(dst, zstore, keys...)
-	pipe.Expire(dst, expire)
+	pipe.ZInterStore(ctx, dst, zstore)
+	pipe.PExpire(ctx, dst, expire)
 
-	_, err := pipe.Exec()
-	return fail.Wrap(err)
+	_, err := pipe.Exec(ctx)
+	return fail.Wrap(storeErr(ctx, err))
 }
 
 func (s newGoredisStoreImp) Unionstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error {
 	redisClient := s.redisClientFunc(ctx)
 
 	pipe := redisClient.Pipeline()
-	zstore := go_redis.ZStore{
+	zstore := &go_redis.ZStore{
+		Keys:      keys,
 		Weights:   weights,
 		Aggregate: aggregate.String(),
 	}
-	pipe.ZUnionStore(dst, zstore, keys...)
-	pipe.Expire(dst, expire)
+	pipe.ZUnionStore(ctx, dst, zstore)
+	pipe.PExpire(ctx, dst, expire)
+
+	_, err := pipe.Exec(ctx)
+	return fail.Wrap(storeErr(ctx, err))
+}
+
+// Subtraction stores key - (subtrahends[0] | subtrahends[1] | ...) into dst. Scores of key are kept.
+// ZDIFFSTORE is used if the server supports it, otherwise a Lua script does the same thing.
+func (s newGoredisStoreImp) Subtraction(ctx context.Context, dst string, expire time.Duration, key string, subtrahends ...string) error {
+	redisClient := s.redisClientFunc(ctx)
+
+	args := []interface{}{"ZDIFFSTORE", dst, len(subtrahends) + 1, key}
+	for _, k := range subtrahends {
+		args = append(args, k)
+	}
+
+	err := redisClient.Do(ctx, args...).Err()
+	if isUnknownCommand(err) {
+		keys := append([]string{dst, key}, subtrahends...)
+		err := go_redis.NewScript(subtractionScript).Run(ctx, redisClient, keys, expire.Milliseconds()).Err()
+		return fail.Wrap(storeErr(ctx, err))
+	}
+	if err != nil {
+		return fail.Wrap(storeErr(ctx, err))
+	}
 
-	_, err := pipe.Exec()
-	return fail.Wrap(err)
+	return fail.Wrap(storeErr(ctx, redisClient.PExpire(ctx, dst, expire).Err()))
 }
 
-// WARING: This function is experimental.
-// Because
-// - Slow
-// - set2's score needs to be much larger than set1' sscore
-// - set2's score needs to be a negative value
-func (s newGoredisStoreImp) Subtraction(ctx context.Context, dst string, expire time.Duration, key1 string, key2 string) error {
+func (s newGoredisStoreImp) TopN(ctx context.Context, dst string, expire time.Duration, n int64, order Order, key string) error {
+	start, stop, err := topNRange(n, order)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+
+	redisClient := s.redisClientFunc(ctx)
+	err = go_redis.NewScript(topNScript).Run(ctx, redisClient, []string{dst, key}, start, stop, expire.Milliseconds()).Err()
+	return fail.Wrap(storeErr(ctx, err))
+}
+
+func (s newGoredisStoreImp) FilterByScore(ctx context.Context, dst string, expire time.Duration, min ScoreBound, max ScoreBound, key string) error {
 	redisClient := s.redisClientFunc(ctx)
+	below, above := outside(min, max)
+	err := go_redis.NewScript(filterByScoreScript).Run(ctx, redisClient, []string{dst, key}, below.String(), above.String(), expire.Milliseconds()).Err()
+	return fail.Wrap(storeErr(ctx, err))
+}
 
-	pipe := redisClient.TxPipeline()
-	zstore := go_redis.ZStore{
-		Weights:   []float64{1, 1},
-		Aggregate: "SUM",
+func (s newGoredisStoreImp) ApplyDelta(ctx context.Context, key string, from string, delta Delta, expire time.Duration) (bool, error) {
+	args, err := applyDeltaArgs(from, delta, expire)
+	if err != nil {
+		return false, fail.Wrap(err)
+	}
+	redisClient := s.redisClientFunc(ctx)
+	applied, err := go_redis.NewScript(applyDeltaScript).Run(ctx, redisClient, []string{key, WatermarkKey(key)}, args...).Int64()
+	if err != nil {
+		return false, fail.Wrap(storeErr(ctx, err))
+	}
+	return applied == 1, nil
+}
+
+func (s newGoredisStoreImp) Watermark(ctx context.Context, key string) (string, bool, error) {
+	redisClient := s.redisClientFunc(ctx)
+	watermark, err := redisClient.Get(ctx, WatermarkKey(key)).Result()
+	if err == go_redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fail.Wrap(storeErr(ctx, err))
+	}
+	return watermark, true, nil
+}
+
+func (s newGoredisStoreImp) SetWatermark(ctx context.Context, key string, watermark string, expire time.Duration) error {
+	redisClient := s.redisClientFunc(ctx)
+	if watermark == "" {
+		return fail.Wrap(storeErr(ctx, redisClient.Del(ctx, WatermarkKey(key)).Err()))
+	}
+	if err := validateWatermarkExpire(expire); err != nil {
+		return fail.Wrap(err)
 	}
-	pipe.ZUnionStore(dst, zstore, key1, key2)
-	pipe.ZRemRangeByScore(dst, "-inf", "(0")
-	pipe.Expire(dst, expire)
+	return fail.Wrap(storeErr(ctx, redisClient.Set(ctx, WatermarkKey(key), watermark, expire).Err()))
+}
+
+func (s newGoredisStoreImp) Delete(ctx context.Context, keys ...string) error {
+	redisClient := s.redisClientFunc(ctx)
+	return fail.Wrap(storeErr(ctx, redisClient.Del(ctx, keys...).Err()))
+}
+
+func (s newGoredisStoreImp) AddDependent(ctx context.Context, key string, dependent string, expire time.Duration) error {
+	redisClient := s.redisClientFunc(ctx)
+	err := go_redis.NewScript(addDependentScript).Run(ctx, redisClient, []string{DependentsKey(key)}, dependent, expire.Milliseconds()).Err()
+	return fail.Wrap(storeErr(ctx, err))
+}
 
-	_, err := pipe.Exec()
-	return fail.Wrap(err)
+func (s newGoredisStoreImp) Dependents(ctx context.Context, key string) ([]string, error) {
+	redisClient := s.redisClientFunc(ctx)
+	dependents, err := redisClient.SMembers(ctx, DependentsKey(key)).Result()
+	if err != nil {
+		return []string{}, fail.Wrap(storeErr(ctx, err))
+	}
+	return dependents, nil
 }
 
 func (s newGoredisStoreImp) Count(ctx context.Context, key string) (int64, error) {
 	redisClient := s.redisClientFunc(ctx)
 
-	cmd := redisClient.ZCard(key)
+	cmd := redisClient.ZCard(ctx, key)
 	if err := cmd.Err(); err != nil {
-		return 0, fail.Wrap(err)
+		return 0, fail.Wrap(storeErr(ctx, err))
 	}
 	return cmd.Val(), nil
 }
+
+func (s newGoredisStoreImp) CountByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound) (int64, error) {
+	redisClient := s.redisClientFunc(ctx)
+
+	cmd := redisClient.ZCount(ctx, key, min.String(), max.String())
+	if err := cmd.Err(); err != nil {
+		return 0, fail.Wrap(storeErr(ctx, err))
+	}
+	return cmd.Val(), nil
+}
+
+// Scores uses ZMSCORE if the server supports it, otherwise ZSCORE for each id.
+func (s newGoredisStoreImp) Scores(ctx context.Context, key string, ids ...ID) (map[ID]float64, error) {
+	scores := map[ID]float64{}
+	if len(ids) == 0 {
+		return scores, nil
+	}
+
+	redisClient := s.redisClientFunc(ctx)
+
+	args := make([]interface{}, 0, len(ids)+2)
+	args = append(args, "ZMSCORE", key)
+	for _, id := range ids {
+		args = append(args, string(id))
+	}
+
+	replies, err := redisClient.Do(ctx, args...).Slice()
+	if isUnknownCommand(err) {
+		pipe := redisClient.Pipeline()
+		cmds := make([]*go_redis.FloatCmd, len(ids), len(ids))
+		for i, id := range ids {
+			cmds[i] = pipe.ZScore(ctx, key, string(id))
+		}
+		// Exec returns Nil when some of ids are not members
+		if _, err := pipe.Exec(ctx); err != nil && err != go_redis.Nil {
+			return scores, fail.Wrap(storeErr(ctx, err))
+		}
+		for i, cmd := range cmds {
+			if cmd.Err() == go_redis.Nil {
+				continue
+			}
+			scores[ids[i]] = cmd.Val()
+		}
+		return scores, nil
+	}
+	if err != nil {
+		return scores, fail.Wrap(storeErr(ctx, err))
+	}
+
+	for i, reply := range replies {
+		// Not a member
+		if reply == nil {
+			continue
+		}
+		value, ok := reply.(string)
+		if !ok {
+			return scores, fail.Wrap(fail.New("Unexpected ZMSCORE reply"), fail.WithParam("reply", reply))
+		}
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return scores, fail.Wrap(err)
+		}
+		scores[ids[i]] = score
+	}
+	return scores, nil
+}
+
+func (s newGoredisStoreImp) Rank(ctx context.Context, key string, id ID, order Order) (int64, bool, error) {
+	redisClient := s.redisClientFunc(ctx)
+
+	var cmd *go_redis.IntCmd
+	switch order {
+	case Asc:
+		cmd = redisClient.ZRank(ctx, key, string(id))
+	case Desc:
+		cmd = redisClient.ZRevRank(ctx, key, string(id))
+	default:
+		return 0, false, fail.Wrap(ErrInvalidOrder, fail.WithParam("order", order))
+	}
+
+	if err := cmd.Err(); err == go_redis.Nil {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, fail.Wrap(storeErr(ctx, err))
+	}
+	return cmd.Val(), true, nil
+}
+
+func (s newGoredisStoreImp) Lock(ctx context.Context, key string, token string, timeout time.Duration) (bool, error) {
+	redisClient := s.redisClientFunc(ctx)
+
+	cmd := redisClient.SetNX(ctx, key, token, timeout)
+	if err := cmd.Err(); err != nil {
+		return false, fail.Wrap(storeErr(ctx, err))
+	}
+	return cmd.Val(), nil
+}
+
+func (s newGoredisStoreImp) Unlock(ctx context.Context, key string, token string) error {
+	redisClient := s.redisClientFunc(ctx)
+
+	err := go_redis.NewScript(unlockScript).Run(ctx, redisClient, []string{key}, token).Err()
+	return fail.Wrap(storeErr(ctx, err))
+}
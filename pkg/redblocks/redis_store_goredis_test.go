@@ -2,10 +2,12 @@ package redblocks_test
 
 import (
 	"context"
+	"errors"
+	"net"
 	"testing"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 	"github.com/google/go-cmp/cmp"
 	"github.com/rerost/redblocks-go/pkg/redblocks"
 )
@@ -124,7 +126,7 @@ func TestGorediStoreTTL(t *testing.T) {
 		t.Error(err)
 	}
 	if !(0 < ttl && ttl <= cacheTime) {
-		t.Errorf("want: 0 < ttl < cacheTime but ttl: %v", ttl)
+		t.Errorf("want: 0 < ttl <= cacheTime but ttl: %v", ttl)
 	}
 
 	emptyKey := key + ":" + "EMPTY"
@@ -132,9 +134,12 @@ func TestGorediStoreTTL(t *testing.T) {
 	if diff := cmp.Diff(err.Error(), "Not found"); diff != "" {
 		t.Errorf(diff)
 	}
+	if !errors.Is(err, redblocks.ErrKeyNotFound) {
+		t.Errorf("want ErrKeyNotFound but got %v", err)
+	}
 
 	notExpireKey := key + ":" + "NOT_EXPIRE"
-	cmd := redisdb.Set(notExpireKey, notExpireKey, 0)
+	cmd := redisdb.Set(ctx, notExpireKey, notExpireKey, 0)
 	if err := cmd.Err(); err != nil {
 		t.Error(err)
 	}
@@ -142,8 +147,11 @@ func TestGorediStoreTTL(t *testing.T) {
 	if diff := cmp.Diff(err.Error(), "Not configured expire"); diff != "" {
 		t.Error(diff)
 	}
+	if !errors.Is(err, redblocks.ErrNoExpiry) {
+		t.Errorf("want ErrNoExpiry but got %v", err)
+	}
 
-	redisdb.Del(notExpireKey)
+	redisdb.Del(ctx, notExpireKey)
 }
 
 func TestGoRedisStoreInterstore(t *testing.T) {
@@ -319,6 +327,23 @@ func TestGoRedisStoreSubtraction(t *testing.T) {
 	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "4", Score: 3}}); diff != "" {
 		t.Errorf(diff)
 	}
+
+	key3 := "TestGoRedisStoreSubtraction3"
+	err = redisStore.Save(ctx, key3, []redblocks.IDWithScore{{ID: "4", Score: 100}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = redisStore.Subtraction(ctx, key, time.Second*100, key1, key2, key3)
+	if err != nil {
+		t.Error(err)
+	}
+	result, err = redisStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{}); diff != "" {
+		t.Errorf(diff)
+	}
 }
 
 func TestGoRedisStoreIDsWithOrder(t *testing.T) {
@@ -411,3 +436,256 @@ func TestGoRedisStoreIDWithScoresWithOrder(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestGoRedisStoreGetIDsByScore(t *testing.T) {
+	redisStore := redblocks.NewGoredisStore(redisdb.WithContext)
+
+	key := "TestGoRedisStoreGetIDsByScore"
+	idsWithScore := []redblocks.IDWithScore{
+		{
+			ID:    "1",
+			Score: 1,
+		},
+		{
+			ID:    "2",
+			Score: 2,
+		},
+		{
+			ID:    "3",
+			Score: 3,
+		},
+	}
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, idsWithScore, time.Second*100)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ids, err := redisStore.GetIDsByScore(ctx, key, redblocks.Exclusive(1), redblocks.PositiveInf, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"2", "3"}); diff != "" {
+		t.Error(diff)
+	}
+
+	result, err := redisStore.GetIDsWithScoreByScore(ctx, key, redblocks.Inclusive(1), redblocks.Inclusive(3), 1, 1, redblocks.Desc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "2", Score: 2}}); diff != "" {
+		t.Error(diff)
+	}
+
+	count, err := redisStore.CountByScore(ctx, key, redblocks.NegativeInf, redblocks.Exclusive(3))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(count, int64(2)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestGoRedisStoreSaveReplaces(t *testing.T) {
+	redisStore := redblocks.NewGoredisStore(redisdb.WithContext)
+	key := "TestGoRedisStoreSaveReplaces"
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "2", Score: 2}, {ID: "3", Score: 3}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ids, err := redisStore.GetIDs(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"2", "3"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	err = redisStore.Save(ctx, key, []redblocks.IDWithScore{}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	exists, err := redisStore.Exists(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(exists, false); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestGoRedisStoreSaveIterator(t *testing.T) {
+	redisStore := redblocks.NewGoredisStore(redisdb.WithContext, redblocks.WithChunkSize(2))
+	key := "TestGoRedisStoreSaveIterator"
+	ctx := context.Background()
+
+	idsWithScore := []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}, {ID: "3", Score: 3}, {ID: "4", Score: 4}, {ID: "5", Score: 5}}
+	err := redisStore.SaveIterator(ctx, key, redblocks.SliceIterator(idsWithScore), 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	result, err := redisStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, idsWithScore); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestGoRedisStoreCanceled(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Dialer: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	redisStore := redblocks.NewGoredisStore(client.WithContext)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := redisStore.GetIDs(ctx, "TestGoRedisStoreCanceled", 0, -1, redblocks.Asc)
+	if !redblocks.IsCanceled(err) {
+		t.Errorf("want CanceledError but got %v", err)
+	}
+}
+
+func TestGoRedisStoreUnavailable(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr:       "localhost:1",
+		MaxRetries: -1,
+	})
+	redisStore := redblocks.NewGoredisStore(client.WithContext)
+
+	_, err := redisStore.Exists(context.Background(), "TestGoRedisStoreUnavailable")
+	if !errors.Is(err, redblocks.ErrStoreUnavailable) {
+		t.Errorf("want ErrStoreUnavailable but got %v", err)
+	}
+}
+
+func TestGoRedisStoreScoresAndRank(t *testing.T) {
+	redisStore := redblocks.NewGoredisStore(redisdb.WithContext)
+	key := "TestGoRedisStoreScoresAndRank"
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}, {ID: "c", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	scores, err := redisStore.Scores(ctx, key, "a", "c", "x")
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(scores, map[redblocks.ID]float64{"a": 1, "c": 2}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	rank, ok, err := redisStore.Rank(ctx, key, "c", redblocks.Desc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff([]interface{}{rank, ok}, []interface{}{int64(0), true}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	_, ok, err = redisStore.Rank(ctx, key, "x", redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ok, false); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestGoRedisStoreTopNAndFilterByScore(t *testing.T) {
+	redisStore := redblocks.NewGoredisStore(redisdb.WithContext)
+	key := "TestGoRedisStoreTopNAndFilterByScore"
+	dst := "TestGoRedisStoreTopNAndFilterByScoreDst"
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}, {ID: "c", Score: 3}, {ID: "d", Score: 4}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = redisStore.TopN(ctx, dst, 100*time.Second, 2, redblocks.Desc, key)
+	if err != nil {
+		t.Error(err)
+	}
+	result, err := redisStore.GetIDsWithScore(ctx, dst, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "c", Score: 3}, {ID: "d", Score: 4}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	err = redisStore.FilterByScore(ctx, dst, 100*time.Second, redblocks.Exclusive(1), redblocks.Inclusive(3), key)
+	if err != nil {
+		t.Error(err)
+	}
+	result, err = redisStore.GetIDsWithScore(ctx, dst, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "b", Score: 2}, {ID: "c", Score: 3}}); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestGoRedisStoreApplyDelta(t *testing.T) {
+	redisStore := redblocks.NewGoredisStore(redisdb.WithContext)
+	key := "TestGoRedisStoreApplyDelta"
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = redisStore.SetWatermark(ctx, key, "v1", 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	delta := redblocks.Delta{
+		Added:       []redblocks.IDWithScore{{ID: "c", Score: 3}},
+		Removed:     []redblocks.ID{"a"},
+		Incremented: []redblocks.IDWithScore{{ID: "b", Score: 5}},
+		Watermark:   "v2",
+	}
+	for _, expected := range []bool{true, false} {
+		applied, err := redisStore.ApplyDelta(ctx, key, "v1", delta, 100*time.Second)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff(applied, expected); diff != "" {
+			t.Errorf(diff)
+		}
+	}
+
+	result, err := redisStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "c", Score: 3}, {ID: "b", Score: 7}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	watermark, ok, err := redisStore.Watermark(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff([]interface{}{watermark, ok}, []interface{}{"v2", true}); diff != "" {
+		t.Errorf(diff)
+	}
+}
@@ -2,6 +2,7 @@ package redblocks_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -132,8 +133,8 @@ func TestRediStoreTTL(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	if !(0 < ttl && ttl < cacheTime) {
-		t.Errorf("want: 0 < ttl < cacheTime but ttl: %v", ttl)
+	if !(0 < ttl && ttl <= cacheTime) {
+		t.Errorf("want: 0 < ttl <= cacheTime but ttl: %v", ttl)
 	}
 
 	emptyKey := key + ":" + "EMPTY"
@@ -141,6 +142,9 @@ func TestRediStoreTTL(t *testing.T) {
 	if diff := cmp.Diff(err.Error(), "Not found"); diff != "" {
 		t.Errorf(diff)
 	}
+	if !errors.Is(err, redblocks.ErrKeyNotFound) {
+		t.Errorf("want ErrKeyNotFound but got %v", err)
+	}
 
 	conn, err := redis.Dial("tcp", "localhost:6379")
 	if err != nil {
@@ -156,6 +160,9 @@ func TestRediStoreTTL(t *testing.T) {
 	if diff := cmp.Diff(err.Error(), "Not configured expire"); diff != "" {
 		t.Errorf(diff)
 	}
+	if !errors.Is(err, redblocks.ErrNoExpiry) {
+		t.Errorf("want ErrNoExpiry but got %v", err)
+	}
 	conn.Do("DEL", notExpireKey)
 }
 
@@ -344,6 +351,67 @@ func TestRedisStoreSubtraction(t *testing.T) {
 	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "4", Score: 3}}); diff != "" {
 		t.Errorf(diff)
 	}
+
+	key3 := "TestRedisStoreSubtraction3"
+	err = redisStore.Save(ctx, key3, []redblocks.IDWithScore{{ID: "4", Score: 100}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = redisStore.Subtraction(ctx, key, time.Second*100, key1, key2, key3)
+	if err != nil {
+		t.Error(err)
+	}
+	result, err = redisStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{}); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestRedisStoreComposeMilliseconds(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:6379") },
+	})
+	key1 := "TestRedisStoreComposeMilliseconds1"
+	key2 := "TestRedisStoreComposeMilliseconds2"
+	ctx := context.Background()
+
+	if err := redisStore.Save(ctx, key1, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}, time.Second*100); err != nil {
+		t.Error(err)
+	}
+	if err := redisStore.Save(ctx, key2, []redblocks.IDWithScore{{ID: "2", Score: 2}}, time.Second*100); err != nil {
+		t.Error(err)
+	}
+
+	expire := 1500 * time.Millisecond
+	tests := map[string]func(dst string) error{
+		"Interstore": func(dst string) error {
+			return redisStore.Interstore(ctx, dst, expire, []float64{1, 1}, redblocks.Sum, key1, key2)
+		},
+		"Unionstore": func(dst string) error {
+			return redisStore.Unionstore(ctx, dst, expire, []float64{1, 1}, redblocks.Sum, key1, key2)
+		},
+		"Subtraction": func(dst string) error {
+			return redisStore.Subtraction(ctx, dst, expire, key1, key2)
+		},
+	}
+	for name, store := range tests {
+		dst := "TestRedisStoreComposeMilliseconds" + name
+		if err := store(dst); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+		ttl, err := redisStore.TTL(ctx, dst)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+		if !(0 < ttl && ttl <= expire) {
+			t.Errorf("%v: want: 0 < ttl <= 1.5s but ttl: %v", name, ttl)
+		}
+	}
 }
 
 func TestRedisStoreIDsWithOrder(t *testing.T) {
@@ -444,3 +512,321 @@ func TestRedisStoreIDWithScoresWithOrder(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestRedisStoreGetIDsByScore(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:6379") },
+	})
+
+	key := "TestRedisStoreGetIDsByScore"
+	idsWithScore := []redblocks.IDWithScore{
+		{
+			ID:    "1",
+			Score: 1,
+		},
+		{
+			ID:    "2",
+			Score: 2,
+		},
+		{
+			ID:    "3",
+			Score: 3,
+		},
+	}
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, idsWithScore, time.Second*100)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ids, err := redisStore.GetIDsByScore(ctx, key, redblocks.Exclusive(1), redblocks.PositiveInf, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"2", "3"}); diff != "" {
+		t.Error(diff)
+	}
+
+	result, err := redisStore.GetIDsWithScoreByScore(ctx, key, redblocks.Inclusive(1), redblocks.Inclusive(3), 1, 1, redblocks.Desc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "2", Score: 2}}); diff != "" {
+		t.Error(diff)
+	}
+
+	count, err := redisStore.CountByScore(ctx, key, redblocks.NegativeInf, redblocks.Exclusive(3))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(count, int64(2)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestRedisStoreSaveReplaces(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:6379") },
+	})
+	key := "TestRedisStoreSaveReplaces"
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "2", Score: 2}, {ID: "3", Score: 3}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ids, err := redisStore.GetIDs(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"2", "3"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	err = redisStore.Save(ctx, key, []redblocks.IDWithScore{}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	exists, err := redisStore.Exists(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(exists, false); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestRedisStoreSaveIterator(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:6379") },
+	}, redblocks.WithChunkSize(2))
+	key := "TestRedisStoreSaveIterator"
+	ctx := context.Background()
+
+	idsWithScore := []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}, {ID: "3", Score: 3}, {ID: "4", Score: 4}, {ID: "5", Score: 5}}
+	err := redisStore.SaveIterator(ctx, key, redblocks.SliceIterator(idsWithScore), 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	result, err := redisStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, idsWithScore); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestRedisStoreSaveIteratorMilliseconds(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:6379") },
+	})
+	key := "TestRedisStoreSaveIteratorMilliseconds"
+	ctx := context.Background()
+
+	err := redisStore.SaveIterator(ctx, key, redblocks.SliceIterator([]redblocks.IDWithScore{{ID: "1", Score: 1}}), 1500*time.Millisecond)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ttl, err := redisStore.TTL(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if !(0 < ttl && ttl <= 1500*time.Millisecond) {
+		t.Errorf("want: 0 < ttl <= 1.5s but ttl: %v", ttl)
+	}
+}
+
+func TestRedisStoreCanceled(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		DialContext: func(ctx context.Context) (redis.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := redisStore.GetIDs(ctx, "TestRedisStoreCanceled", 0, -1, redblocks.Asc)
+	if !redblocks.IsCanceled(err) {
+		t.Errorf("want CanceledError but got %v", err)
+	}
+}
+
+func TestRedisStoreUnavailable(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		Dial: func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:1") },
+	})
+
+	_, err := redisStore.Exists(context.Background(), "TestRedisStoreUnavailable")
+	if !errors.Is(err, redblocks.ErrStoreUnavailable) {
+		t.Errorf("want ErrStoreUnavailable but got %v", err)
+	}
+}
+
+func TestRedisStoreScoresAndRank(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:6379") },
+	})
+	key := "TestRedisStoreScoresAndRank"
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}, {ID: "c", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	scores, err := redisStore.Scores(ctx, key, "a", "c", "x")
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(scores, map[redblocks.ID]float64{"a": 1, "c": 2}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	rank, ok, err := redisStore.Rank(ctx, key, "c", redblocks.Desc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff([]interface{}{rank, ok}, []interface{}{int64(0), true}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	_, ok, err = redisStore.Rank(ctx, key, "x", redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ok, false); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestRedisStoreTopNAndFilterByScore(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:6379") },
+	})
+	key := "TestRedisStoreTopNAndFilterByScore"
+	dst := "TestRedisStoreTopNAndFilterByScoreDst"
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}, {ID: "c", Score: 3}, {ID: "d", Score: 4}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = redisStore.TopN(ctx, dst, 100*time.Second, 2, redblocks.Desc, key)
+	if err != nil {
+		t.Error(err)
+	}
+	result, err := redisStore.GetIDsWithScore(ctx, dst, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "c", Score: 3}, {ID: "d", Score: 4}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	err = redisStore.FilterByScore(ctx, dst, 100*time.Second, redblocks.Exclusive(1), redblocks.Inclusive(3), key)
+	if err != nil {
+		t.Error(err)
+	}
+	result, err = redisStore.GetIDsWithScore(ctx, dst, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "b", Score: 2}, {ID: "c", Score: 3}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	// Expire below a second is not truncated to 0
+	for name, store := range map[string]func() error{
+		"TopN": func() error {
+			return redisStore.TopN(ctx, dst, 500*time.Millisecond, 2, redblocks.Desc, key)
+		},
+		"FilterByScore": func() error {
+			return redisStore.FilterByScore(ctx, dst, 500*time.Millisecond, redblocks.NegativeInf, redblocks.PositiveInf, key)
+		},
+	} {
+		if err := store(); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+		exists, err := redisStore.Exists(ctx, dst)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+		if diff := cmp.Diff(exists, true); diff != "" {
+			t.Errorf("%v: %v", name, diff)
+		}
+	}
+}
+
+func TestRedisStoreApplyDelta(t *testing.T) {
+	redisStore := redblocks.NewRedisStore(&redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", "localhost:6379") },
+	})
+	key := "TestRedisStoreApplyDelta"
+	ctx := context.Background()
+
+	err := redisStore.Save(ctx, key, []redblocks.IDWithScore{{ID: "a", Score: 1}, {ID: "b", Score: 2}}, 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	err = redisStore.SetWatermark(ctx, key, "v1", 100*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	delta := redblocks.Delta{
+		Added:       []redblocks.IDWithScore{{ID: "c", Score: 3}},
+		Removed:     []redblocks.ID{"a"},
+		Incremented: []redblocks.IDWithScore{{ID: "b", Score: 5}},
+		Watermark:   "v2",
+	}
+	for _, expected := range []bool{true, false} {
+		applied, err := redisStore.ApplyDelta(ctx, key, "v1", delta, 100*time.Second)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff(applied, expected); diff != "" {
+			t.Errorf(diff)
+		}
+	}
+
+	result, err := redisStore.GetIDsWithScore(ctx, key, 0, -1, redblocks.Asc)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(result, []redblocks.IDWithScore{{ID: "c", Score: 3}, {ID: "b", Score: 7}}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	watermark, ok, err := redisStore.Watermark(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff([]interface{}{watermark, ok}, []interface{}{"v2", true}); diff != "" {
+		t.Errorf(diff)
+	}
+}
@@ -0,0 +1,221 @@
+package redblocks
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+type RefreshStatus struct {
+	Key         string
+	NextRefresh time.Time
+	LastSuccess time.Time
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// Refresher keeps registered sets warm by updating them before TTL goes below NotAvailableTTL.
+type Refresher struct {
+	store   Store
+	opt     RefresherOption
+	mu      sync.Mutex
+	entries map[string]*refreshEntry
+}
+
+type refreshEntry struct {
+	set    ComposedSet
+	height int // 0 for leaf sets, otherwise 1 + max height of children
+	status RefreshStatus
+}
+
+func NewRefresher(store Store, opts ...RefresherOption) *Refresher {
+	opt, _ := RefresherOptionsToRefresherOption(opts)
+	return &Refresher{
+		store:   store,
+		opt:     opt,
+		entries: map[string]*refreshEntry{},
+	}
+}
+
+// Register adds sets and all of their children. Registering the same key twice is ignored.
+func (r *Refresher) Register(sets ...ComposedSet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, set := range sets {
+		r.register(set)
+	}
+}
+
+func (r *Refresher) register(set ComposedSet) int {
+	height := 0
+	for _, child := range Children(set) {
+		if h := r.register(child) + 1; h > height {
+			height = h
+		}
+	}
+
+	key := set.Key()
+	if e, ok := r.entries[key]; ok {
+		if height > e.height {
+			e.height = height
+		}
+		return e.height
+	}
+	r.entries[key] = &refreshEntry{set: set, height: height, status: RefreshStatus{Key: key}}
+	return height
+}
+
+// Run refreshes sets every Interval until ctx is done.
+func (r *Refresher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.opt.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.RefreshDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshDue refreshes sets whose schedule has come. Children are refreshed before their parents.
+func (r *Refresher) RefreshDue(ctx context.Context) {
+	now := r.opt.Now()
+
+	r.mu.Lock()
+	levels := map[int][]*refreshEntry{}
+	maxHeight := 0
+	for _, e := range r.entries {
+		if e.status.NextRefresh.After(now) {
+			continue
+		}
+		levels[e.height] = append(levels[e.height], e)
+		if e.height > maxHeight {
+			maxHeight = e.height
+		}
+	}
+	r.mu.Unlock()
+
+	for height := 0; height <= maxHeight; height++ {
+		if ctx.Err() != nil {
+			return
+		}
+		r.refreshAll(ctx, levels[height])
+	}
+}
+
+func (r *Refresher) refreshAll(ctx context.Context, entries []*refreshEntry) {
+	queue := make(chan *refreshEntry)
+	var wg sync.WaitGroup
+	for i := 0; i < r.opt.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range queue {
+				r.refresh(ctx, e)
+			}
+		}()
+	}
+
+	for _, e := range entries {
+		queue <- e
+	}
+	close(queue)
+	wg.Wait()
+}
+
+func (r *Refresher) refresh(ctx context.Context, e *refreshEntry) {
+	ttl, err := remaining(ctx, r.store, e.set.Key())
+	if err != nil {
+		r.failed(e, err)
+		return
+	}
+
+	// Another process may have refreshed it already
+	if untilNotAvailable := ttl - e.set.NotAvailableTTL(); ttl >= 0 && untilNotAvailable > r.opt.Lead {
+		r.schedule(e, untilNotAvailable-r.opt.Lead)
+		return
+	}
+
+	if err := e.set.Update(ctx); err != nil {
+		r.failed(e, err)
+		return
+	}
+
+	r.mu.Lock()
+	e.status.LastSuccess = r.opt.Now()
+	r.mu.Unlock()
+	r.schedule(e, e.set.CacheTime()-e.set.NotAvailableTTL()-r.opt.Lead)
+}
+
+func (r *Refresher) failed(e *refreshEntry, err error) {
+	r.mu.Lock()
+	e.status.LastError = err
+	e.status.LastErrorAt = r.opt.Now()
+	r.mu.Unlock()
+	r.schedule(e, r.opt.RetryInterval)
+}
+
+func (r *Refresher) schedule(e *refreshEntry, after time.Duration) {
+	if r.opt.Jitter > 0 {
+		after -= time.Duration(rand.Int63n(int64(r.opt.Jitter)))
+	}
+	if after < r.opt.Interval {
+		after = r.opt.Interval
+	}
+
+	r.mu.Lock()
+	e.status.NextRefresh = r.opt.Now().Add(after)
+	r.mu.Unlock()
+}
+
+// Notify makes sets changed by event due, so the next RefreshDue checks them again, e.g. when another instance refreshed or invalidated them.
+// Sets which are still fresh are only rescheduled. It implements Listener.
+func (r *Refresher) Notify(ctx context.Context, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.Type == BusReconnected {
+		for _, e := range r.entries {
+			e.status.NextRefresh = time.Time{}
+		}
+		return
+	}
+	for _, key := range event.Keys {
+		if e, ok := r.entries[key]; ok {
+			e.status.NextRefresh = time.Time{}
+		}
+	}
+}
+
+func (r *Refresher) Status(key string) (RefreshStatus, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[key]
+	if !ok {
+		return RefreshStatus{}, false
+	}
+	return e.status, true
+}
+
+// Statuses returns status of all registered sets ordered by key
+func (r *Refresher) Statuses() []RefreshStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]RefreshStatus, 0, len(r.entries))
+	for _, e := range r.entries {
+		statuses = append(statuses, e.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Key < statuses[j].Key
+	})
+	return statuses
+}
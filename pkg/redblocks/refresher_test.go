@@ -0,0 +1,142 @@
+package redblocks_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+func TestRefresher(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	store := redblocks.NewMemoryStore(redblocks.WithClock(clock.Now))
+	var calls int32
+	tokyo := redblocks.Compose(slowSetImp{
+		scoredSetImp: scoredSetImp{name: "TestRefresherTokyo", idsWithScore: []redblocks.IDWithScore{{ID: "1", Score: 1}}},
+		calls:        &calls,
+	}, store)
+	osaka := redblocks.Compose(NewScoredSet("TestRefresherOsaka", []redblocks.IDWithScore{{ID: "2", Score: 2}}), store)
+	union := redblocks.NewUnionSet(store, time.Second*100, time.Second*10, []float64{1, 1}, redblocks.Sum, tokyo, osaka)
+	failing := redblocks.Compose(failingSetImp{scoredSetImp{name: "TestRefresherFailing"}}, store)
+
+	refresher := redblocks.NewRefresher(store, redblocks.WithRefreshClock(clock.Now), redblocks.WithRefreshJitter(-1))
+	refresher.Register(union, failing)
+	ctx := context.Background()
+
+	refresher.RefreshDue(ctx)
+	if diff := cmp.Diff(atomic.LoadInt32(&calls), int32(1)); diff != "" {
+		t.Errorf(diff)
+	}
+	for _, set := range []redblocks.ComposedSet{tokyo, osaka, union} {
+		status, ok := refresher.Status(set.Key())
+		if !ok {
+			t.Errorf("%v is not registered", set.Key())
+		}
+		if diff := cmp.Diff(status.LastSuccess, clock.Now()); diff != "" {
+			t.Errorf(diff)
+		}
+		// CacheTime - NotAvailableTTL - Lead
+		if diff := cmp.Diff(status.NextRefresh, clock.Now().Add(time.Second*88)); diff != "" {
+			t.Errorf(diff)
+		}
+	}
+	status, _ := refresher.Status(failing.Key())
+	if status.LastError == nil {
+		t.Errorf("Expected not nil")
+	}
+	if diff := cmp.Diff(len(refresher.Statuses()), 4); diff != "" {
+		t.Errorf(diff)
+	}
+
+	clock.Add(time.Second * 50)
+	refresher.RefreshDue(ctx)
+	if diff := cmp.Diff(atomic.LoadInt32(&calls), int32(1)); diff != "" {
+		t.Errorf(diff)
+	}
+
+	// Still available, so readers never see a miss
+	clock.Add(time.Second * 39)
+	available, err := union.Available(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(available, true); diff != "" {
+		t.Errorf(diff)
+	}
+	refresher.RefreshDue(ctx)
+	if diff := cmp.Diff(atomic.LoadInt32(&calls), int32(2)); diff != "" {
+		t.Errorf(diff)
+	}
+	ttl, err := store.TTL(ctx, union.Key())
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ttl, time.Second*100); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestRefresherRun(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	tokyo := redblocks.Compose(NewScoredSet("TestRefresherRun", []redblocks.IDWithScore{{ID: "1", Score: 1}}), store)
+	refresher := redblocks.NewRefresher(store, redblocks.WithRefreshInterval(time.Millisecond))
+	refresher.Register(tokyo)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if err := refresher.Run(ctx); err != nil {
+		t.Error(err)
+	}
+
+	exists, err := store.Exists(context.Background(), tokyo.Key())
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(exists, true); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestRefresherNegativeWorkers(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	tokyo := redblocks.Compose(NewScoredSet("TestRefresherNegativeWorkers", []redblocks.IDWithScore{{ID: "1", Score: 1}}), store)
+	refresher := redblocks.NewRefresher(store, redblocks.WithRefreshWorkers(-1))
+	refresher.Register(tokyo)
+
+	done := make(chan struct{})
+	go func() {
+		refresher.RefreshDue(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RefreshDue does not return")
+	}
+
+	status, _ := refresher.Status(tokyo.Key())
+	if status.LastSuccess.IsZero() {
+		t.Errorf("Expected refreshed")
+	}
+}
+
+func TestRefresherNegativeInterval(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	tokyo := redblocks.Compose(NewScoredSet("TestRefresherNegativeInterval", []redblocks.IDWithScore{{ID: "1", Score: 1}}), store)
+	refresher := redblocks.NewRefresher(store, redblocks.WithRefreshInterval(-time.Second))
+	refresher.Register(tokyo)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if err := refresher.Run(ctx); err != nil {
+		t.Error(err)
+	}
+
+	status, _ := refresher.Status(tokyo.Key())
+	if status.LastSuccess.IsZero() {
+		t.Errorf("Expected refreshed")
+	}
+}
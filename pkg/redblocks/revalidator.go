@@ -0,0 +1,73 @@
+package redblocks
+
+import (
+	"context"
+	"sync"
+)
+
+// Revalidator runs Update in background goroutines for stale-while-revalidate Warmup.
+// It can be shared by many sets, so the number of concurrent updates is bounded across them.
+type Revalidator struct {
+	sem      chan struct{}
+	onError  func(key string, err error)
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	inflight map[string]struct{}
+}
+
+// NewRevalidator returns Revalidator which runs at most concurrency updates at once.
+// onError is called with the key of the set when a background update fails. It can be nil.
+func NewRevalidator(concurrency int, onError func(key string, err error)) *Revalidator {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Revalidator{
+		sem:      make(chan struct{}, concurrency),
+		onError:  onError,
+		inflight: map[string]struct{}{},
+	}
+}
+
+// Revalidate starts updating set in background and returns immediately.
+// It does nothing when the same key is already being updated or all slots are in use, the next Warmup will try again.
+func (r *Revalidator) Revalidate(ctx context.Context, set WithUpdate) bool {
+	key := set.Key()
+
+	r.mu.Lock()
+	if _, ok := r.inflight[key]; ok {
+		r.mu.Unlock()
+		return false
+	}
+	select {
+	case r.sem <- struct{}{}:
+	default:
+		r.mu.Unlock()
+		return false
+	}
+	r.inflight[key] = struct{}{}
+	r.mu.Unlock()
+
+	// Request scoped ctx is canceled soon after the response
+	ctx = context.WithoutCancel(ctx)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mu.Lock()
+			delete(r.inflight, key)
+			r.mu.Unlock()
+			<-r.sem
+		}()
+
+		if err := set.Update(ctx); err != nil && r.onError != nil {
+			r.onError(key, err)
+		}
+	}()
+	return true
+}
+
+// Wait blocks until all background updates finish
+func (r *Revalidator) Wait() {
+	r.wg.Wait()
+}
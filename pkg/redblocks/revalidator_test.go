@@ -0,0 +1,93 @@
+package redblocks_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+type failingSetImp struct {
+	scoredSetImp
+}
+
+func (s failingSetImp) Get(ctx context.Context) ([]redblocks.IDWithScore, error) {
+	return nil, errors.New("failed")
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	var calls int32
+	slow := slowSetImp{
+		scoredSetImp: scoredSetImp{name: "TestStaleWhileRevalidate", idsWithScore: []redblocks.IDWithScore{{ID: "fresh", Score: 1}}},
+		calls:        &calls,
+		delay:        time.Millisecond * 50,
+	}
+	revalidator := redblocks.NewRevalidator(1, nil)
+	set := redblocks.ComposeIDs(redblocks.ComposeWarmup(redblocks.ComposeUpdate(slow, store), store, redblocks.WithStaleWhileRevalidate(revalidator)), store)
+	ctx := context.Background()
+
+	// Not exists yet, so Warmup blocks
+	if err := set.Warmup(ctx); err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(atomic.LoadInt32(&calls), int32(1)); diff != "" {
+		t.Errorf(diff)
+	}
+
+	// Stale data which is below NotAvailableTTL
+	err := store.Save(ctx, set.Key(), []redblocks.IDWithScore{{ID: "stale"}}, time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := set.Warmup(ctx); err != nil {
+			t.Error(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= slow.delay {
+		t.Errorf("Warmup blocked for %v", elapsed)
+	}
+
+	revalidator.Wait()
+	if diff := cmp.Diff(atomic.LoadInt32(&calls), int32(2)); diff != "" {
+		t.Errorf(diff)
+	}
+	available, err := set.Available(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(available, true); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestStaleWhileRevalidateError(t *testing.T) {
+	store := redblocks.NewMemoryStore()
+	var failedKey string
+	revalidator := redblocks.NewRevalidator(1, func(key string, err error) {
+		failedKey = key
+	})
+	failing := failingSetImp{scoredSetImp{name: "TestStaleWhileRevalidateError"}}
+	set := redblocks.ComposeWarmup(redblocks.ComposeUpdate(failing, store), store, redblocks.WithStaleWhileRevalidate(revalidator))
+	ctx := context.Background()
+
+	err := store.Save(ctx, set.Key(), []redblocks.IDWithScore{{ID: "stale"}}, time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err := set.Warmup(ctx); err != nil {
+		t.Error(err)
+	}
+	revalidator.Wait()
+	if diff := cmp.Diff(failedKey, set.Key()); diff != "" {
+		t.Errorf(diff)
+	}
+}
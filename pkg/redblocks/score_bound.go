@@ -0,0 +1,58 @@
+package redblocks
+
+import (
+	"math"
+	"strconv"
+)
+
+// ScoreBound is one end of a score range. See https://redis.io/commands/ZRANGEBYSCORE
+type ScoreBound struct {
+	Score     float64
+	Exclusive bool
+}
+
+var (
+	NegativeInf = ScoreBound{Score: math.Inf(-1)}
+	PositiveInf = ScoreBound{Score: math.Inf(1)}
+)
+
+func Inclusive(score float64) ScoreBound {
+	return ScoreBound{Score: score}
+}
+
+func Exclusive(score float64) ScoreBound {
+	return ScoreBound{Score: score, Exclusive: true}
+}
+
+func (b ScoreBound) String() string {
+	var s string
+	switch {
+	case math.IsInf(b.Score, -1):
+		s = "-inf"
+	case math.IsInf(b.Score, 1):
+		s = "+inf"
+	default:
+		s = strconv.FormatFloat(b.Score, 'f', -1, 64)
+	}
+
+	if b.Exclusive {
+		return "(" + s
+	}
+	return s
+}
+
+// AboveMin reports whether score is on the inner side of b when b is used as a lower bound.
+func (b ScoreBound) AboveMin(score float64) bool {
+	if b.Exclusive {
+		return score > b.Score
+	}
+	return score >= b.Score
+}
+
+// BelowMax reports whether score is on the inner side of b when b is used as an upper bound.
+func (b ScoreBound) BelowMax(score float64) bool {
+	if b.Exclusive {
+		return score < b.Score
+	}
+	return score <= b.Score
+}
@@ -20,13 +20,20 @@ type ComposedSet interface {
 	Warmup(ctx context.Context) error
 	IDs(ctx context.Context, opts ...PagenationOption) ([]ID, error)
 	IDsWithScore(ctx context.Context, opts ...PagenationOption) ([]IDWithScore, error)
+	IDsWithCursor(ctx context.Context, cursor Cursor, count int64, opts ...PagenationOption) ([]ID, Cursor, error)
+	IDsWithScoreWithCursor(ctx context.Context, cursor Cursor, count int64, opts ...PagenationOption) ([]IDWithScore, Cursor, error)
 	Count(ctx context.Context) (int64, error)
+	CountByScore(ctx context.Context, min ScoreBound, max ScoreBound) (int64, error)
+	Contains(ctx context.Context, ids ...ID) (map[ID]bool, error)
+	Score(ctx context.Context, ids ...ID) (map[ID]float64, error) // Scores of ids which are members
+	Rank(ctx context.Context, id ID, order Order) (int64, bool, error)
+	Invalidate(ctx context.Context) error // Deletes the set and sets derived from it
 }
 
-func Compose(wrapped Set, store Store) ComposedSet {
-	return setToComposed(wrapped, store)
+func Compose(wrapped Set, store Store, opts ...ComposeOption) ComposedSet {
+	return setToComposed(wrapped, store, opts...)
 }
 
-func setToComposed(set Set, store Store) ComposedSet {
-	return ComposeIDs(ComposeWarmup(ComposeUpdate(set, store), store), store)
+func setToComposed(set Set, store Store, opts ...ComposeOption) ComposedSet {
+	return ComposeIDs(ComposeWarmup(ComposeUpdate(set, store, opts...), store), store)
 }
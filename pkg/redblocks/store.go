@@ -3,6 +3,8 @@ package redblocks
 import (
 	"context"
 	"time"
+
+	"github.com/rerost/redblocks-go/internal/fail"
 )
 
 type IDWithScore struct {
@@ -14,12 +16,53 @@ type ID string
 
 type Store interface {
 	Save(ctx context.Context, key string, idsWithScore []IDWithScore, expire time.Duration) error
+	SaveIterator(ctx context.Context, key string, it IDWithScoreIterator, expire time.Duration) error
 	GetIDs(ctx context.Context, key string, head int64, tail int64, order Order) ([]ID, error)
 	GetIDsWithScore(ctx context.Context, key string, head int64, tail int64, order Order) ([]IDWithScore, error)
+	GetIDsByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]ID, error)
+	GetIDsWithScoreByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]IDWithScore, error)
 	Exists(ctx context.Context, key string) (bool, error)
 	TTL(ctx context.Context, key string) (time.Duration, error)
 	Interstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error
 	Unionstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error
-	Subtraction(ctx context.Context, dst string, expire time.Duration, key1 string, key2 string) error
+	Subtraction(ctx context.Context, dst string, expire time.Duration, key string, subtrahends ...string) error
+	TopN(ctx context.Context, dst string, expire time.Duration, n int64, order Order, key string) error // Keeps the first n members of key in order. Desc keeps the highest scores.
+	FilterByScore(ctx context.Context, dst string, expire time.Duration, min ScoreBound, max ScoreBound, key string) error
 	Count(ctx context.Context, key string) (int64, error)
+	Delete(ctx context.Context, keys ...string) error
+	AddDependent(ctx context.Context, key string, dependent string, expire time.Duration) error // Records that dependent is derived from key for at least expire
+	Dependents(ctx context.Context, key string) ([]string, error)
+	// ApplyDelta applies delta to key and moves its watermark to delta.Watermark, only when key exists and the watermark is still from.
+	// It reports whether delta is applied.
+	ApplyDelta(ctx context.Context, key string, from string, delta Delta, expire time.Duration) (bool, error)
+	Watermark(ctx context.Context, key string) (string, bool, error)
+	SetWatermark(ctx context.Context, key string, watermark string, expire time.Duration) error // Empty watermark removes it
+	CountByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound) (int64, error)
+	Scores(ctx context.Context, key string, ids ...ID) (map[ID]float64, error) // Scores of ids which are members of key
+	Rank(ctx context.Context, key string, id ID, order Order) (int64, bool, error)
+	Lock(ctx context.Context, key string, token string, timeout time.Duration) (bool, error)
+	Unlock(ctx context.Context, key string, token string) error
+}
+
+// remaining returns TTL of key, or -1 when it does not exist
+func remaining(ctx context.Context, store Store, key string) (time.Duration, error) {
+	exists, err := store.Exists(ctx, key)
+	if err != nil {
+		return 0, fail.Wrap(err)
+	}
+	if !exists {
+		return -1, nil
+	}
+
+	ttl, err := store.TTL(ctx, key)
+	return ttl, fail.Wrap(err)
+}
+
+// tempKey returns a unique key to write a new generation of key before renaming it
+func tempKey(key string) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	return key + ":tmp:" + token, nil
 }
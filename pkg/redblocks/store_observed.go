@@ -0,0 +1,208 @@
+package redblocks
+
+import (
+	"context"
+	"time"
+)
+
+type observedStoreImp struct {
+	store Store
+	o     Observer
+}
+
+// NewObservedStore reports latency of every call to store to o.
+// Sets composed with the returned store also report cache and refresh events to o.
+func NewObservedStore(store Store, o Observer) Store {
+	return observedStoreImp{store: store, o: o}
+}
+
+func (s observedStoreImp) observer() Observer {
+	return s.o
+}
+
+func (s observedStoreImp) Unwrap() Store {
+	return s.store
+}
+
+func (s observedStoreImp) observe(ctx context.Context, method string, key string, start time.Time, err error) {
+	s.o.StoreCall(ctx, method, key, time.Since(start), err)
+}
+
+func (s observedStoreImp) Save(ctx context.Context, key string, idsWithScore []IDWithScore, expire time.Duration) error {
+	start := time.Now()
+	err := s.store.Save(ctx, key, idsWithScore, expire)
+	s.observe(ctx, "Save", key, start, err)
+	return err
+}
+
+func (s observedStoreImp) SaveIterator(ctx context.Context, key string, it IDWithScoreIterator, expire time.Duration) error {
+	start := time.Now()
+	err := s.store.SaveIterator(ctx, key, it, expire)
+	s.observe(ctx, "SaveIterator", key, start, err)
+	return err
+}
+
+func (s observedStoreImp) GetIDs(ctx context.Context, key string, head int64, tail int64, order Order) ([]ID, error) {
+	start := time.Now()
+	ids, err := s.store.GetIDs(ctx, key, head, tail, order)
+	s.observe(ctx, "GetIDs", key, start, err)
+	return ids, err
+}
+
+func (s observedStoreImp) GetIDsWithScore(ctx context.Context, key string, head int64, tail int64, order Order) ([]IDWithScore, error) {
+	start := time.Now()
+	idsWithScore, err := s.store.GetIDsWithScore(ctx, key, head, tail, order)
+	s.observe(ctx, "GetIDsWithScore", key, start, err)
+	return idsWithScore, err
+}
+
+func (s observedStoreImp) GetIDsByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]ID, error) {
+	start := time.Now()
+	ids, err := s.store.GetIDsByScore(ctx, key, min, max, offset, count, order)
+	s.observe(ctx, "GetIDsByScore", key, start, err)
+	return ids, err
+}
+
+func (s observedStoreImp) GetIDsWithScoreByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound, offset int64, count int64, order Order) ([]IDWithScore, error) {
+	start := time.Now()
+	idsWithScore, err := s.store.GetIDsWithScoreByScore(ctx, key, min, max, offset, count, order)
+	s.observe(ctx, "GetIDsWithScoreByScore", key, start, err)
+	return idsWithScore, err
+}
+
+func (s observedStoreImp) Exists(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	exists, err := s.store.Exists(ctx, key)
+	s.observe(ctx, "Exists", key, start, err)
+	return exists, err
+}
+
+func (s observedStoreImp) TTL(ctx context.Context, key string) (time.Duration, error) {
+	start := time.Now()
+	ttl, err := s.store.TTL(ctx, key)
+	s.observe(ctx, "TTL", key, start, err)
+	return ttl, err
+}
+
+func (s observedStoreImp) Interstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error {
+	start := time.Now()
+	err := s.store.Interstore(ctx, dst, expire, weights, aggregate, keys...)
+	s.observe(ctx, "Interstore", dst, start, err)
+	return err
+}
+
+func (s observedStoreImp) Unionstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error {
+	start := time.Now()
+	err := s.store.Unionstore(ctx, dst, expire, weights, aggregate, keys...)
+	s.observe(ctx, "Unionstore", dst, start, err)
+	return err
+}
+
+func (s observedStoreImp) Subtraction(ctx context.Context, dst string, expire time.Duration, key string, subtrahends ...string) error {
+	start := time.Now()
+	err := s.store.Subtraction(ctx, dst, expire, key, subtrahends...)
+	s.observe(ctx, "Subtraction", dst, start, err)
+	return err
+}
+
+func (s observedStoreImp) TopN(ctx context.Context, dst string, expire time.Duration, n int64, order Order, key string) error {
+	start := time.Now()
+	err := s.store.TopN(ctx, dst, expire, n, order, key)
+	s.observe(ctx, "TopN", dst, start, err)
+	return err
+}
+
+func (s observedStoreImp) FilterByScore(ctx context.Context, dst string, expire time.Duration, min ScoreBound, max ScoreBound, key string) error {
+	start := time.Now()
+	err := s.store.FilterByScore(ctx, dst, expire, min, max, key)
+	s.observe(ctx, "FilterByScore", dst, start, err)
+	return err
+}
+
+func (s observedStoreImp) ApplyDelta(ctx context.Context, key string, from string, delta Delta, expire time.Duration) (bool, error) {
+	start := time.Now()
+	applied, err := s.store.ApplyDelta(ctx, key, from, delta, expire)
+	s.observe(ctx, "ApplyDelta", key, start, err)
+	return applied, err
+}
+
+func (s observedStoreImp) Watermark(ctx context.Context, key string) (string, bool, error) {
+	start := time.Now()
+	watermark, ok, err := s.store.Watermark(ctx, key)
+	s.observe(ctx, "Watermark", key, start, err)
+	return watermark, ok, err
+}
+
+func (s observedStoreImp) SetWatermark(ctx context.Context, key string, watermark string, expire time.Duration) error {
+	start := time.Now()
+	err := s.store.SetWatermark(ctx, key, watermark, expire)
+	s.observe(ctx, "SetWatermark", key, start, err)
+	return err
+}
+
+func (s observedStoreImp) Delete(ctx context.Context, keys ...string) error {
+	start := time.Now()
+	err := s.store.Delete(ctx, keys...)
+	var key string
+	if len(keys) > 0 {
+		key = keys[0]
+	}
+	s.observe(ctx, "Delete", key, start, err)
+	return err
+}
+
+func (s observedStoreImp) AddDependent(ctx context.Context, key string, dependent string, expire time.Duration) error {
+	start := time.Now()
+	err := s.store.AddDependent(ctx, key, dependent, expire)
+	s.observe(ctx, "AddDependent", key, start, err)
+	return err
+}
+
+func (s observedStoreImp) Dependents(ctx context.Context, key string) ([]string, error) {
+	start := time.Now()
+	dependents, err := s.store.Dependents(ctx, key)
+	s.observe(ctx, "Dependents", key, start, err)
+	return dependents, err
+}
+
+func (s observedStoreImp) Count(ctx context.Context, key string) (int64, error) {
+	start := time.Now()
+	count, err := s.store.Count(ctx, key)
+	s.observe(ctx, "Count", key, start, err)
+	return count, err
+}
+
+func (s observedStoreImp) CountByScore(ctx context.Context, key string, min ScoreBound, max ScoreBound) (int64, error) {
+	start := time.Now()
+	count, err := s.store.CountByScore(ctx, key, min, max)
+	s.observe(ctx, "CountByScore", key, start, err)
+	return count, err
+}
+
+func (s observedStoreImp) Scores(ctx context.Context, key string, ids ...ID) (map[ID]float64, error) {
+	start := time.Now()
+	scores, err := s.store.Scores(ctx, key, ids...)
+	s.observe(ctx, "Scores", key, start, err)
+	return scores, err
+}
+
+func (s observedStoreImp) Rank(ctx context.Context, key string, id ID, order Order) (int64, bool, error) {
+	start := time.Now()
+	rank, ok, err := s.store.Rank(ctx, key, id, order)
+	s.observe(ctx, "Rank", key, start, err)
+	return rank, ok, err
+}
+
+func (s observedStoreImp) Lock(ctx context.Context, key string, token string, timeout time.Duration) (bool, error) {
+	start := time.Now()
+	ok, err := s.store.Lock(ctx, key, token, timeout)
+	s.observe(ctx, "Lock", key, start, err)
+	return ok, err
+}
+
+func (s observedStoreImp) Unlock(ctx context.Context, key string, token string) error {
+	start := time.Now()
+	err := s.store.Unlock(ctx, key, token)
+	s.observe(ctx, "Unlock", key, start, err)
+	return err
+}
@@ -0,0 +1,72 @@
+package redblocks
+
+import (
+	"context"
+	"time"
+)
+
+type publishingStoreImp struct {
+	Store
+	bus     Bus
+	onError func(key string, err error)
+}
+
+// NewPublishingStore publishes KeySaved and KeyDeleted to bus when store writes or deletes sets.
+// A failed publish does not fail the write. onError is called with it instead. It can be nil.
+func NewPublishingStore(store Store, bus Bus, onError func(key string, err error)) Store {
+	return publishingStoreImp{Store: store, bus: bus, onError: onError}
+}
+
+func (s publishingStoreImp) Unwrap() Store {
+	return s.Store
+}
+
+func (s publishingStoreImp) publish(ctx context.Context, eventType EventType, keys []string, err error) error {
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	if perr := s.bus.Publish(ctx, Event{Type: eventType, Keys: keys}); perr != nil && s.onError != nil {
+		s.onError(keys[0], perr)
+	}
+	return nil
+}
+
+func (s publishingStoreImp) Save(ctx context.Context, key string, idsWithScore []IDWithScore, expire time.Duration) error {
+	return s.publish(ctx, KeySaved, []string{key}, s.Store.Save(ctx, key, idsWithScore, expire))
+}
+
+func (s publishingStoreImp) SaveIterator(ctx context.Context, key string, it IDWithScoreIterator, expire time.Duration) error {
+	return s.publish(ctx, KeySaved, []string{key}, s.Store.SaveIterator(ctx, key, it, expire))
+}
+
+func (s publishingStoreImp) Interstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error {
+	return s.publish(ctx, KeySaved, []string{dst}, s.Store.Interstore(ctx, dst, expire, weights, aggregate, keys...))
+}
+
+func (s publishingStoreImp) Unionstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate Aggregate, keys ...string) error {
+	return s.publish(ctx, KeySaved, []string{dst}, s.Store.Unionstore(ctx, dst, expire, weights, aggregate, keys...))
+}
+
+func (s publishingStoreImp) Subtraction(ctx context.Context, dst string, expire time.Duration, key string, subtrahends ...string) error {
+	return s.publish(ctx, KeySaved, []string{dst}, s.Store.Subtraction(ctx, dst, expire, key, subtrahends...))
+}
+
+func (s publishingStoreImp) TopN(ctx context.Context, dst string, expire time.Duration, n int64, order Order, key string) error {
+	return s.publish(ctx, KeySaved, []string{dst}, s.Store.TopN(ctx, dst, expire, n, order, key))
+}
+
+func (s publishingStoreImp) FilterByScore(ctx context.Context, dst string, expire time.Duration, min ScoreBound, max ScoreBound, key string) error {
+	return s.publish(ctx, KeySaved, []string{dst}, s.Store.FilterByScore(ctx, dst, expire, min, max, key))
+}
+
+func (s publishingStoreImp) ApplyDelta(ctx context.Context, key string, from string, delta Delta, expire time.Duration) (bool, error) {
+	applied, err := s.Store.ApplyDelta(ctx, key, from, delta, expire)
+	if !applied {
+		return applied, err
+	}
+	return applied, s.publish(ctx, KeySaved, []string{key}, err)
+}
+
+func (s publishingStoreImp) Delete(ctx context.Context, keys ...string) error {
+	return s.publish(ctx, KeyDeleted, keys, s.Store.Delete(ctx, keys...))
+}
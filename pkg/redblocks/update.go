@@ -4,7 +4,7 @@ import (
 	"context"
 	"reflect"
 
-	"github.com/srvc/fail"
+	"github.com/rerost/redblocks-go/internal/fail"
 )
 
 type WithUpdate interface {
@@ -17,17 +17,46 @@ type WithUpdate interface {
 type withUpdateImp struct {
 	Set
 	store Store
+	opt   ComposeOption
 }
 
-func ComposeUpdate(set Set, store Store) WithUpdate {
-	return withUpdateImp{Set: set, store: store}
+func ComposeUpdate(set Set, store Store, opts ...ComposeOption) WithUpdate {
+	opt, _ := ComposeOptionsToComposeOption(opts)
+	return withUpdateImp{Set: set, store: store, opt: opt}
 }
 
+// Key is `[prefix:]name[@version]:suffix`. name is the type name of the set unless WithKey is given.
 func (c withUpdateImp) Key() string {
-	return reflect.TypeOf(c.Set).String() + ":" + c.Set.KeySuffix()
+	key := c.opt.Key
+	if key == "" {
+		key = reflect.TypeOf(c.Set).String()
+	}
+	if c.opt.Version != "" {
+		key = key + "@" + c.opt.Version
+	}
+	return prefixKey(c.opt.Prefix, key+":"+c.Set.KeySuffix())
+}
+
+func (c withUpdateImp) KeyPrefix() string {
+	return c.opt.Prefix
 }
 
 func (c withUpdateImp) Update(ctx context.Context) error {
+	return observeUpdate(ctx, c.store, c.Key(), c.update)
+}
+
+func (c withUpdateImp) update(ctx context.Context) error {
+	if delta, ok := c.Set.(DeltaSet); ok {
+		return fail.Wrap(c.updateDelta(ctx, delta))
+	}
+	if stream, ok := c.Set.(StreamSet); ok {
+		it, err := stream.Stream(ctx)
+		if err != nil {
+			return fail.Wrap(err)
+		}
+		return fail.Wrap(c.store.SaveIterator(ctx, c.Key(), it, c.CacheTime()))
+	}
+
 	r, err := c.Get(ctx)
 	if err != nil {
 		return fail.Wrap(err)
@@ -3,7 +3,7 @@ package redblocks
 import (
 	"context"
 
-	"github.com/srvc/fail"
+	"github.com/rerost/redblocks-go/internal/fail"
 )
 
 type WithWarmup interface {
@@ -14,10 +14,12 @@ type WithWarmup interface {
 type withWarmupImp struct {
 	WithUpdate
 	store Store
+	opt   WarmupOption
 }
 
-func ComposeWarmup(withUpdate WithUpdate, store Store) WithWarmup {
-	return withWarmupImp{WithUpdate: withUpdate, store: store}
+func ComposeWarmup(withUpdate WithUpdate, store Store, opts ...WarmupOption) WithWarmup {
+	opt, _ := WarmupOptionsToWarmupOption(opts)
+	return withWarmupImp{WithUpdate: withUpdate, store: store, opt: opt}
 }
 
 func (c withWarmupImp) Warmup(ctx context.Context) error {
@@ -25,9 +27,43 @@ func (c withWarmupImp) Warmup(ctx context.Context) error {
 	if err != nil {
 		return fail.Wrap(err)
 	}
+	o, _ := observerOf(c.store)
+	if available {
+		o.CacheHit(ctx, c.Key())
+		return nil
+	}
 
-	if !available {
-		return fail.Wrap(c.Update(ctx))
+	if c.opt.Revalidator != nil {
+		exists, err := c.store.Exists(ctx, c.Key())
+		if err != nil {
+			return fail.Wrap(err)
+		}
+		if exists {
+			o.StaleServe(ctx, c.Key())
+			c.opt.Revalidator.Revalidate(ctx, c.WithUpdate)
+			return nil
+		}
 	}
-	return nil
+
+	o.CacheMiss(ctx, c.Key())
+	return fail.Wrap(c.Update(ctx))
+}
+
+func (c withWarmupImp) Children() []ComposedSet {
+	return Children(c.WithUpdate)
+}
+
+func (c withWarmupImp) CanonicalKey() string {
+	if canonical, ok := c.WithUpdate.(Canonical); ok {
+		return canonical.CanonicalKey()
+	}
+	return c.Key()
+}
+
+func (c withWarmupImp) KeyPrefix() string {
+	return KeyPrefix(c.WithUpdate)
+}
+
+func (c withWarmupImp) Describe() Operation {
+	return Describe(c.WithUpdate)
 }
@@ -0,0 +1,36 @@
+package redblocksotel
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TracerOption struct {
+	TracerProvider trace.TracerProvider
+}
+
+func TracerOptionsToTracerOption(opts []TracerOption) (TracerOption, error) {
+	opt := TracerOption{}
+	for _, o := range opts {
+		if o.TracerProvider != nil {
+			opt.TracerProvider = o.TracerProvider
+		}
+	}
+	if opt.TracerProvider == nil {
+		opt.TracerProvider = otel.GetTracerProvider()
+	}
+
+	return opt, nil
+}
+
+// WithTracerProvider uses tp instead of the global TracerProvider.
+func WithTracerProvider(tp trace.TracerProvider) TracerOption {
+	return TracerOption{
+		TracerProvider: tp,
+	}
+}
+
+func tracer(opts []TracerOption) trace.Tracer {
+	opt, _ := TracerOptionsToTracerOption(opts)
+	return opt.TracerProvider.Tracer(instrumentationName)
+}
@@ -0,0 +1,154 @@
+package redblocksotel
+
+import (
+	"context"
+
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedSetImp starts a span for reads. Reads warm up through tracedWarmupImp, so Set.Warmup is a child of the read span.
+type tracedSetImp struct {
+	redblocks.ComposedSet
+	tracer trace.Tracer
+}
+
+type tracedWarmupImp struct {
+	redblocks.ComposedSet
+	tracer trace.Tracer
+}
+
+// TraceSet starts a span for reads, Warmup and Update of set. Trace children before composing them,
+// so warming up a composed set shows up as a tree of spans.
+func TraceSet(set redblocks.ComposedSet, opts ...TracerOption) redblocks.ComposedSet {
+	tracer := tracer(opts)
+	warmup := tracedWarmupImp{ComposedSet: set, tracer: tracer}
+	holder, ok := set.(redblocks.StoreHolder)
+	if !ok {
+		return warmup
+	}
+	return tracedSetImp{ComposedSet: redblocks.ComposeIDs(warmup, holder.Store()), tracer: tracer}
+}
+
+func startSet(ctx context.Context, tracer trace.Tracer, method string, key string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "Set."+method, trace.WithAttributes(attribute.String(keyAttribute, key)))
+}
+
+func (s tracedSetImp) IDs(ctx context.Context, opts ...redblocks.PagenationOption) ([]redblocks.ID, error) {
+	ctx, span := startSet(ctx, s.tracer, "IDs", s.Key())
+	defer span.End()
+	ids, err := s.ComposedSet.IDs(ctx, opts...)
+	return ids, end(span, err)
+}
+
+func (s tracedSetImp) IDsWithScore(ctx context.Context, opts ...redblocks.PagenationOption) ([]redblocks.IDWithScore, error) {
+	ctx, span := startSet(ctx, s.tracer, "IDsWithScore", s.Key())
+	defer span.End()
+	idsWithScore, err := s.ComposedSet.IDsWithScore(ctx, opts...)
+	return idsWithScore, end(span, err)
+}
+
+func (s tracedSetImp) IDsWithCursor(ctx context.Context, cursor redblocks.Cursor, count int64, opts ...redblocks.PagenationOption) ([]redblocks.ID, redblocks.Cursor, error) {
+	ctx, span := startSet(ctx, s.tracer, "IDsWithCursor", s.Key())
+	defer span.End()
+	ids, next, err := s.ComposedSet.IDsWithCursor(ctx, cursor, count, opts...)
+	return ids, next, end(span, err)
+}
+
+func (s tracedSetImp) IDsWithScoreWithCursor(ctx context.Context, cursor redblocks.Cursor, count int64, opts ...redblocks.PagenationOption) ([]redblocks.IDWithScore, redblocks.Cursor, error) {
+	ctx, span := startSet(ctx, s.tracer, "IDsWithScoreWithCursor", s.Key())
+	defer span.End()
+	idsWithScore, next, err := s.ComposedSet.IDsWithScoreWithCursor(ctx, cursor, count, opts...)
+	return idsWithScore, next, end(span, err)
+}
+
+func (s tracedSetImp) Count(ctx context.Context) (int64, error) {
+	ctx, span := startSet(ctx, s.tracer, "Count", s.Key())
+	defer span.End()
+	count, err := s.ComposedSet.Count(ctx)
+	return count, end(span, err)
+}
+
+func (s tracedSetImp) CountByScore(ctx context.Context, min redblocks.ScoreBound, max redblocks.ScoreBound) (int64, error) {
+	ctx, span := startSet(ctx, s.tracer, "CountByScore", s.Key())
+	defer span.End()
+	count, err := s.ComposedSet.CountByScore(ctx, min, max)
+	return count, end(span, err)
+}
+
+func (s tracedSetImp) Contains(ctx context.Context, ids ...redblocks.ID) (map[redblocks.ID]bool, error) {
+	ctx, span := startSet(ctx, s.tracer, "Contains", s.Key())
+	defer span.End()
+	contains, err := s.ComposedSet.Contains(ctx, ids...)
+	return contains, end(span, err)
+}
+
+func (s tracedSetImp) Score(ctx context.Context, ids ...redblocks.ID) (map[redblocks.ID]float64, error) {
+	ctx, span := startSet(ctx, s.tracer, "Score", s.Key())
+	defer span.End()
+	scores, err := s.ComposedSet.Score(ctx, ids...)
+	return scores, end(span, err)
+}
+
+func (s tracedSetImp) Rank(ctx context.Context, id redblocks.ID, order redblocks.Order) (int64, bool, error) {
+	ctx, span := startSet(ctx, s.tracer, "Rank", s.Key())
+	defer span.End()
+	rank, ok, err := s.ComposedSet.Rank(ctx, id, order)
+	return rank, ok, end(span, err)
+}
+
+func (s tracedSetImp) Children() []redblocks.ComposedSet {
+	return redblocks.Children(s.ComposedSet)
+}
+
+func (s tracedSetImp) CanonicalKey() string {
+	return redblocks.CanonicalKey(s.ComposedSet)
+}
+
+func (s tracedSetImp) KeyPrefix() string {
+	return redblocks.KeyPrefix(s.ComposedSet)
+}
+
+func (s tracedSetImp) Describe() redblocks.Operation {
+	return redblocks.Describe(s.ComposedSet)
+}
+
+func (s tracedSetImp) Store() redblocks.Store {
+	return s.ComposedSet.(redblocks.StoreHolder).Store()
+}
+
+func (s tracedWarmupImp) Warmup(ctx context.Context) error {
+	ctx, span := startSet(ctx, s.tracer, "Warmup", s.Key())
+	defer span.End()
+	return end(span, s.ComposedSet.Warmup(ctx))
+}
+
+func (s tracedWarmupImp) Update(ctx context.Context) error {
+	ctx, span := startSet(ctx, s.tracer, "Update", s.Key())
+	defer span.End()
+	return end(span, s.ComposedSet.Update(ctx))
+}
+
+func (s tracedWarmupImp) Children() []redblocks.ComposedSet {
+	return redblocks.Children(s.ComposedSet)
+}
+
+func (s tracedWarmupImp) CanonicalKey() string {
+	return redblocks.CanonicalKey(s.ComposedSet)
+}
+
+func (s tracedWarmupImp) KeyPrefix() string {
+	return redblocks.KeyPrefix(s.ComposedSet)
+}
+
+func (s tracedWarmupImp) Describe() redblocks.Operation {
+	return redblocks.Describe(s.ComposedSet)
+}
+
+func (s tracedWarmupImp) Store() redblocks.Store {
+	if holder, ok := s.ComposedSet.(redblocks.StoreHolder); ok {
+		return holder.Store()
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package redblocksotel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+	"github.com/rerost/redblocks-go/pkg/redblocksotel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanTree returns names of ended spans by the name of their parent. Names are followed by the key and the number of members.
+func spanTree(recorder *tracetest.SpanRecorder) map[string][]string {
+	names := map[string]string{}
+	for _, span := range recorder.Ended() {
+		name := span.Name()
+		for _, attr := range span.Attributes() {
+			if attr.Key == "redblocks.key" {
+				name += " " + attr.Value.AsString()
+			}
+			if attr.Key == "redblocks.members" {
+				name += " " + attr.Value.Emit()
+			}
+		}
+		names[span.SpanContext().SpanID().String()] = name
+	}
+
+	tree := map[string][]string{}
+	for _, span := range recorder.Ended() {
+		parent := names[span.Parent().SpanID().String()]
+		tree[parent] = append(tree[parent], names[span.SpanContext().SpanID().String()])
+	}
+	return tree
+}
+
+func TestTraceSetRead(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opt := redblocksotel.WithTracerProvider(tp)
+
+	store := redblocksotel.NewTracedStore(redblocks.NewMemoryStore(), opt)
+	tokyo := redblocksotel.TraceSet(redblocks.Compose(regionSetImp{region: "tokyo"}, store, redblocks.WithKey("region")), opt)
+	ctx := context.Background()
+
+	ids, err := tokyo.IDs(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff(ids, []redblocks.ID{"1", "tokyo"}); diff != "" {
+		t.Errorf(diff)
+	}
+
+	want := map[string][]string{
+		"": {"Set.IDs region:tokyo"},
+		"Set.IDs region:tokyo": {
+			"Store.Exists region:tokyo",
+			"Set.Warmup region:tokyo",
+			"Store.GetIDs region:tokyo 2",
+		},
+		"Set.Warmup region:tokyo": {
+			"Store.Exists region:tokyo",
+			"Store.Save region:tokyo 2",
+		},
+	}
+	if diff := cmp.Diff(spanTree(recorder), want); diff != "" {
+		t.Errorf(diff)
+	}
+}
+
+func TestTraceSetUpdate(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opt := redblocksotel.WithTracerProvider(tp)
+
+	store := redblocksotel.NewTracedStore(redblocks.NewMemoryStore(), opt)
+	tokyo := redblocksotel.TraceSet(redblocks.Compose(regionSetImp{region: "tokyo"}, store, redblocks.WithKey("region")), opt)
+	union := redblocksotel.TraceSet(redblocks.NewUnionSet(store, time.Second*100, time.Second*10, nil, redblocks.Sum, tokyo), opt)
+	ctx := context.Background()
+
+	if err := union.Update(ctx); err != nil {
+		t.Error(err)
+	}
+
+	want := map[string][]string{
+		"": {"Set.Update " + union.Key()},
+		"Set.Update " + union.Key(): {
+			"Set.Warmup region:tokyo",
+			"Store.Unionstore " + union.Key(),
+			"Store.AddDependent region:tokyo",
+		},
+		"Set.Warmup region:tokyo": {
+			"Store.Exists region:tokyo",
+			"Store.Save region:tokyo 2",
+		},
+	}
+	if diff := cmp.Diff(spanTree(recorder), want); diff != "" {
+		t.Errorf(diff)
+	}
+}
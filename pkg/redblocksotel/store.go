@@ -0,0 +1,264 @@
+// Package redblocksotel traces redblocks stores and sets with OpenTelemetry.
+package redblocksotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/rerost/redblocks-go/pkg/redblocksotel"
+
+const (
+	keyAttribute     = "redblocks.key"
+	membersAttribute = "redblocks.members"
+)
+
+type tracedStoreImp struct {
+	store  redblocks.Store
+	tracer trace.Tracer
+}
+
+// NewTracedStore starts a span from ctx for every call to store, with the key, the Redis command and the number of members.
+func NewTracedStore(store redblocks.Store, opts ...TracerOption) redblocks.Store {
+	return tracedStoreImp{store: store, tracer: tracer(opts)}
+}
+
+func (s tracedStoreImp) Unwrap() redblocks.Store {
+	return s.store
+}
+
+func (s tracedStoreImp) start(ctx context.Context, method string, command string, key string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "Store."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation", command),
+		attribute.String(keyAttribute, key),
+	))
+}
+
+func end(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func members(span trace.Span, n int) {
+	span.SetAttributes(attribute.Int(membersAttribute, n))
+}
+
+func rangeCommand(order redblocks.Order, byScore bool) string {
+	command := "ZRANGE"
+	if byScore {
+		command = "ZRANGEBYSCORE"
+	}
+	if order == redblocks.Desc {
+		return "ZREV" + command[1:]
+	}
+	return command
+}
+
+func (s tracedStoreImp) Save(ctx context.Context, key string, idsWithScore []redblocks.IDWithScore, expire time.Duration) error {
+	ctx, span := s.start(ctx, "Save", "ZADD", key)
+	defer span.End()
+	members(span, len(idsWithScore))
+	return end(span, s.store.Save(ctx, key, idsWithScore, expire))
+}
+
+func (s tracedStoreImp) SaveIterator(ctx context.Context, key string, it redblocks.IDWithScoreIterator, expire time.Duration) error {
+	ctx, span := s.start(ctx, "SaveIterator", "ZADD", key)
+	defer span.End()
+	counter := &countingIterator{it: it}
+	err := s.store.SaveIterator(ctx, key, counter, expire)
+	members(span, counter.n)
+	return end(span, err)
+}
+
+func (s tracedStoreImp) GetIDs(ctx context.Context, key string, head int64, tail int64, order redblocks.Order) ([]redblocks.ID, error) {
+	ctx, span := s.start(ctx, "GetIDs", rangeCommand(order, false), key)
+	defer span.End()
+	ids, err := s.store.GetIDs(ctx, key, head, tail, order)
+	members(span, len(ids))
+	return ids, end(span, err)
+}
+
+func (s tracedStoreImp) GetIDsWithScore(ctx context.Context, key string, head int64, tail int64, order redblocks.Order) ([]redblocks.IDWithScore, error) {
+	ctx, span := s.start(ctx, "GetIDsWithScore", rangeCommand(order, false), key)
+	defer span.End()
+	idsWithScore, err := s.store.GetIDsWithScore(ctx, key, head, tail, order)
+	members(span, len(idsWithScore))
+	return idsWithScore, end(span, err)
+}
+
+func (s tracedStoreImp) GetIDsByScore(ctx context.Context, key string, min redblocks.ScoreBound, max redblocks.ScoreBound, offset int64, count int64, order redblocks.Order) ([]redblocks.ID, error) {
+	ctx, span := s.start(ctx, "GetIDsByScore", rangeCommand(order, true), key)
+	defer span.End()
+	ids, err := s.store.GetIDsByScore(ctx, key, min, max, offset, count, order)
+	members(span, len(ids))
+	return ids, end(span, err)
+}
+
+func (s tracedStoreImp) GetIDsWithScoreByScore(ctx context.Context, key string, min redblocks.ScoreBound, max redblocks.ScoreBound, offset int64, count int64, order redblocks.Order) ([]redblocks.IDWithScore, error) {
+	ctx, span := s.start(ctx, "GetIDsWithScoreByScore", rangeCommand(order, true), key)
+	defer span.End()
+	idsWithScore, err := s.store.GetIDsWithScoreByScore(ctx, key, min, max, offset, count, order)
+	members(span, len(idsWithScore))
+	return idsWithScore, end(span, err)
+}
+
+func (s tracedStoreImp) Exists(ctx context.Context, key string) (bool, error) {
+	ctx, span := s.start(ctx, "Exists", "EXISTS", key)
+	defer span.End()
+	exists, err := s.store.Exists(ctx, key)
+	return exists, end(span, err)
+}
+
+func (s tracedStoreImp) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ctx, span := s.start(ctx, "TTL", "TTL", key)
+	defer span.End()
+	ttl, err := s.store.TTL(ctx, key)
+	return ttl, end(span, err)
+}
+
+func (s tracedStoreImp) Interstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate redblocks.Aggregate, keys ...string) error {
+	ctx, span := s.start(ctx, "Interstore", "ZINTERSTORE", dst)
+	defer span.End()
+	return end(span, s.store.Interstore(ctx, dst, expire, weights, aggregate, keys...))
+}
+
+func (s tracedStoreImp) Unionstore(ctx context.Context, dst string, expire time.Duration, weights []float64, aggregate redblocks.Aggregate, keys ...string) error {
+	ctx, span := s.start(ctx, "Unionstore", "ZUNIONSTORE", dst)
+	defer span.End()
+	return end(span, s.store.Unionstore(ctx, dst, expire, weights, aggregate, keys...))
+}
+
+func (s tracedStoreImp) Subtraction(ctx context.Context, dst string, expire time.Duration, key string, subtrahends ...string) error {
+	ctx, span := s.start(ctx, "Subtraction", "ZDIFFSTORE", dst)
+	defer span.End()
+	return end(span, s.store.Subtraction(ctx, dst, expire, key, subtrahends...))
+}
+
+func (s tracedStoreImp) TopN(ctx context.Context, dst string, expire time.Duration, n int64, order redblocks.Order, key string) error {
+	ctx, span := s.start(ctx, "TopN", "ZREMRANGEBYRANK", dst)
+	defer span.End()
+	return end(span, s.store.TopN(ctx, dst, expire, n, order, key))
+}
+
+func (s tracedStoreImp) FilterByScore(ctx context.Context, dst string, expire time.Duration, min redblocks.ScoreBound, max redblocks.ScoreBound, key string) error {
+	ctx, span := s.start(ctx, "FilterByScore", "ZREMRANGEBYSCORE", dst)
+	defer span.End()
+	return end(span, s.store.FilterByScore(ctx, dst, expire, min, max, key))
+}
+
+func (s tracedStoreImp) Count(ctx context.Context, key string) (int64, error) {
+	ctx, span := s.start(ctx, "Count", "ZCARD", key)
+	defer span.End()
+	count, err := s.store.Count(ctx, key)
+	members(span, int(count))
+	return count, end(span, err)
+}
+
+func (s tracedStoreImp) CountByScore(ctx context.Context, key string, min redblocks.ScoreBound, max redblocks.ScoreBound) (int64, error) {
+	ctx, span := s.start(ctx, "CountByScore", "ZCOUNT", key)
+	defer span.End()
+	count, err := s.store.CountByScore(ctx, key, min, max)
+	members(span, int(count))
+	return count, end(span, err)
+}
+
+func (s tracedStoreImp) Scores(ctx context.Context, key string, ids ...redblocks.ID) (map[redblocks.ID]float64, error) {
+	ctx, span := s.start(ctx, "Scores", "ZMSCORE", key)
+	defer span.End()
+	scores, err := s.store.Scores(ctx, key, ids...)
+	members(span, len(scores))
+	return scores, end(span, err)
+}
+
+func (s tracedStoreImp) Rank(ctx context.Context, key string, id redblocks.ID, order redblocks.Order) (int64, bool, error) {
+	command := "ZRANK"
+	if order == redblocks.Desc {
+		command = "ZREVRANK"
+	}
+	ctx, span := s.start(ctx, "Rank", command, key)
+	defer span.End()
+	rank, ok, err := s.store.Rank(ctx, key, id, order)
+	return rank, ok, end(span, err)
+}
+
+func (s tracedStoreImp) ApplyDelta(ctx context.Context, key string, from string, delta redblocks.Delta, expire time.Duration) (bool, error) {
+	ctx, span := s.start(ctx, "ApplyDelta", "EVAL", key)
+	defer span.End()
+	members(span, len(delta.Added)+len(delta.Removed)+len(delta.Incremented))
+	applied, err := s.store.ApplyDelta(ctx, key, from, delta, expire)
+	return applied, end(span, err)
+}
+
+func (s tracedStoreImp) Watermark(ctx context.Context, key string) (string, bool, error) {
+	ctx, span := s.start(ctx, "Watermark", "GET", key)
+	defer span.End()
+	watermark, ok, err := s.store.Watermark(ctx, key)
+	return watermark, ok, end(span, err)
+}
+
+func (s tracedStoreImp) SetWatermark(ctx context.Context, key string, watermark string, expire time.Duration) error {
+	ctx, span := s.start(ctx, "SetWatermark", "SET", key)
+	defer span.End()
+	return end(span, s.store.SetWatermark(ctx, key, watermark, expire))
+}
+
+func (s tracedStoreImp) Delete(ctx context.Context, keys ...string) error {
+	var key string
+	if len(keys) > 0 {
+		key = keys[0]
+	}
+	ctx, span := s.start(ctx, "Delete", "DEL", key)
+	defer span.End()
+	members(span, len(keys))
+	return end(span, s.store.Delete(ctx, keys...))
+}
+
+func (s tracedStoreImp) AddDependent(ctx context.Context, key string, dependent string, expire time.Duration) error {
+	ctx, span := s.start(ctx, "AddDependent", "SADD", key)
+	defer span.End()
+	return end(span, s.store.AddDependent(ctx, key, dependent, expire))
+}
+
+func (s tracedStoreImp) Dependents(ctx context.Context, key string) ([]string, error) {
+	ctx, span := s.start(ctx, "Dependents", "SMEMBERS", key)
+	defer span.End()
+	dependents, err := s.store.Dependents(ctx, key)
+	members(span, len(dependents))
+	return dependents, end(span, err)
+}
+
+func (s tracedStoreImp) Lock(ctx context.Context, key string, token string, timeout time.Duration) (bool, error) {
+	ctx, span := s.start(ctx, "Lock", "SET", key)
+	defer span.End()
+	ok, err := s.store.Lock(ctx, key, token, timeout)
+	span.SetAttributes(attribute.Bool("redblocks.locked", ok))
+	return ok, end(span, err)
+}
+
+func (s tracedStoreImp) Unlock(ctx context.Context, key string, token string) error {
+	ctx, span := s.start(ctx, "Unlock", "EVAL", key)
+	defer span.End()
+	return end(span, s.store.Unlock(ctx, key, token))
+}
+
+type countingIterator struct {
+	it redblocks.IDWithScoreIterator
+	n  int
+}
+
+func (c *countingIterator) Next(ctx context.Context) (redblocks.IDWithScore, bool, error) {
+	idWithScore, ok, err := c.it.Next(ctx)
+	if ok && err == nil {
+		c.n++
+	}
+	return idWithScore, ok, err
+}
@@ -0,0 +1,73 @@
+package redblocksotel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+	"github.com/rerost/redblocks-go/pkg/redblocksotel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type regionSetImp struct {
+	region string
+}
+
+func (s regionSetImp) KeySuffix() string {
+	return s.region
+}
+
+func (s regionSetImp) Get(ctx context.Context) ([]redblocks.IDWithScore, error) {
+	return []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: redblocks.ID(s.region), Score: 2}}, nil
+}
+
+func (s regionSetImp) CacheTime() time.Duration {
+	return time.Second * 100
+}
+
+func (s regionSetImp) NotAvailableTTL() time.Duration {
+	return time.Second * 10
+}
+
+func TestTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opt := redblocksotel.WithTracerProvider(tp)
+
+	store := redblocksotel.NewTracedStore(redblocks.NewMemoryStore(), opt)
+	tokyo := redblocksotel.TraceSet(redblocks.Compose(regionSetImp{region: "tokyo"}, store, redblocks.WithKey("region")), opt)
+	osaka := redblocksotel.TraceSet(redblocks.Compose(regionSetImp{region: "osaka"}, store, redblocks.WithKey("region")), opt)
+	union := redblocksotel.TraceSet(redblocks.NewUnionSetWithOptions(store, time.Second*100, time.Second*10, nil, redblocks.Sum, []redblocks.OperatorOption{redblocks.WithChildConcurrency(1)}, tokyo, osaka), opt)
+
+	ctx := context.Background()
+	if err := union.Warmup(ctx); err != nil {
+		t.Error(err)
+	}
+
+	got := spanTree(recorder)
+	want := map[string][]string{
+		"": {"Set.Warmup " + union.Key()},
+		"Set.Warmup " + union.Key(): {
+			"Store.Exists " + union.Key(),
+			"Set.Warmup region:tokyo",
+			"Set.Warmup region:osaka",
+			"Store.Unionstore " + union.Key(),
+			"Store.AddDependent region:tokyo",
+			"Store.AddDependent region:osaka",
+		},
+		"Set.Warmup region:tokyo": {
+			"Store.Exists region:tokyo",
+			"Store.Save region:tokyo 2",
+		},
+		"Set.Warmup region:osaka": {
+			"Store.Exists region:osaka",
+			"Store.Save region:osaka 2",
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf(diff)
+	}
+}
@@ -0,0 +1,125 @@
+// Package redblocksprom exports redblocks events as Prometheus metrics.
+package redblocksprom
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+)
+
+// Collector is a redblocks.Observer and a prometheus.Collector.
+//
+//	collector := redblocksprom.NewCollector(redblocksprom.WithSetLabel(redblocks.SetName))
+//	prometheus.MustRegister(collector)
+//	store := redblocks.NewObservedStore(redblocks.NewRedisStore(pool), collector)
+type Collector struct {
+	opt CollectorOption
+
+	hits            *prometheus.CounterVec
+	misses          *prometheus.CounterVec
+	staleServes     *prometheus.CounterVec
+	refreshing      *prometheus.GaugeVec
+	refreshDuration *prometheus.HistogramVec
+	members         *prometheus.GaugeVec
+	storeDuration   *prometheus.HistogramVec
+}
+
+func NewCollector(opts ...CollectorOption) *Collector {
+	opt, _ := CollectorOptionsToCollectorOption(opts)
+	return &Collector{
+		opt: opt,
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opt.Namespace,
+			Name:      "cache_hits_total",
+			Help:      "Number of reads served without updating the set.",
+		}, []string{"set"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opt.Namespace,
+			Name:      "cache_misses_total",
+			Help:      "Number of reads which updated the set before reading it.",
+		}, []string{"set"}),
+		staleServes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opt.Namespace,
+			Name:      "stale_serves_total",
+			Help:      "Number of reads served from an expiring set while it is updated.",
+		}, []string{"set"}),
+		refreshing: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: opt.Namespace,
+			Name:      "refreshes_in_progress",
+			Help:      "Number of updates in progress.",
+		}, []string{"set"}),
+		refreshDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opt.Namespace,
+			Name:      "refresh_duration_seconds",
+			Help:      "Duration of updates of sets.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"set", "result"}),
+		members: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: opt.Namespace,
+			Name:      "members",
+			Help:      "Number of members after the last update.",
+		}, []string{"set"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opt.Namespace,
+			Name:      "store_call_duration_seconds",
+			Help:      "Duration of calls to the store.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "set", "result"}),
+	}
+}
+
+var _ redblocks.Observer = (*Collector)(nil)
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.hits, c.misses, c.staleServes, c.refreshing, c.refreshDuration, c.members, c.storeDuration}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+func (c *Collector) CacheHit(ctx context.Context, key string) {
+	c.hits.WithLabelValues(c.opt.SetLabel(key)).Inc()
+}
+
+func (c *Collector) CacheMiss(ctx context.Context, key string) {
+	c.misses.WithLabelValues(c.opt.SetLabel(key)).Inc()
+}
+
+func (c *Collector) StaleServe(ctx context.Context, key string) {
+	c.staleServes.WithLabelValues(c.opt.SetLabel(key)).Inc()
+}
+
+func (c *Collector) RefreshStart(ctx context.Context, key string) {
+	c.refreshing.WithLabelValues(c.opt.SetLabel(key)).Inc()
+}
+
+func (c *Collector) RefreshEnd(ctx context.Context, key string, duration time.Duration, count int64, err error) {
+	set := c.opt.SetLabel(key)
+	c.refreshing.WithLabelValues(set).Dec()
+	c.refreshDuration.WithLabelValues(set, result(err)).Observe(duration.Seconds())
+	if err == nil && count >= 0 {
+		c.members.WithLabelValues(set).Set(float64(count))
+	}
+}
+
+func (c *Collector) StoreCall(ctx context.Context, method string, key string, duration time.Duration, err error) {
+	c.storeDuration.WithLabelValues(method, c.opt.SetLabel(key), result(err)).Observe(duration.Seconds())
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,84 @@
+package redblocksprom_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rerost/redblocks-go/pkg/redblocks"
+	"github.com/rerost/redblocks-go/pkg/redblocksprom"
+)
+
+type regionSetImp struct {
+	region string
+}
+
+func (s regionSetImp) KeySuffix() string {
+	return s.region
+}
+
+func (s regionSetImp) Get(ctx context.Context) ([]redblocks.IDWithScore, error) {
+	return []redblocks.IDWithScore{{ID: "1", Score: 1}, {ID: "2", Score: 2}}, nil
+}
+
+func (s regionSetImp) CacheTime() time.Duration {
+	return time.Second * 100
+}
+
+func (s regionSetImp) NotAvailableTTL() time.Duration {
+	return time.Second * 10
+}
+
+func TestCollector(t *testing.T) {
+	collector := redblocksprom.NewCollector(redblocksprom.WithSetLabel(redblocks.SetName))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	store := redblocks.NewObservedStore(redblocks.NewMemoryStore(), collector)
+	ctx := context.Background()
+	for _, region := range []string{"tokyo", "osaka", "tokyo"} {
+		set := redblocks.Compose(regionSetImp{region: region}, store, redblocks.WithKey("region"))
+		if _, err := set.IDs(ctx); err != nil {
+			t.Error(err)
+		}
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range m.GetLabel() {
+				name += " " + label.GetName() + "=" + label.GetValue()
+			}
+			switch {
+			case m.Counter != nil:
+				got[name] = m.Counter.GetValue()
+			case m.Gauge != nil:
+				got[name] = m.Gauge.GetValue()
+			case m.Histogram != nil:
+				got[name] = float64(m.Histogram.GetSampleCount())
+			}
+		}
+	}
+
+	want := map[string]float64{
+		"redblocks_cache_hits_total set=region":                                         1,
+		"redblocks_cache_misses_total set=region":                                       2,
+		"redblocks_refreshes_in_progress set=region":                                    0,
+		"redblocks_refresh_duration_seconds result=success set=region":                  2,
+		"redblocks_members set=region":                                                  2,
+		"redblocks_store_call_duration_seconds method=Count result=success set=region":  2,
+		"redblocks_store_call_duration_seconds method=Exists result=success set=region": 5,
+		"redblocks_store_call_duration_seconds method=GetIDs result=success set=region": 3,
+		"redblocks_store_call_duration_seconds method=Save result=success set=region":   2,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf(diff)
+	}
+}
@@ -0,0 +1,36 @@
+package redblocksprom
+
+type CollectorOption struct {
+	Namespace string
+	SetLabel  func(key string) string // Value of the set label. The key itself by default
+}
+
+func CollectorOptionsToCollectorOption(opts []CollectorOption) (CollectorOption, error) {
+	opt := CollectorOption{
+		Namespace: "redblocks",
+		SetLabel:  func(key string) string { return key },
+	}
+	for _, o := range opts {
+		if o.Namespace != "" {
+			opt.Namespace = o.Namespace
+		}
+		if o.SetLabel != nil {
+			opt.SetLabel = o.SetLabel
+		}
+	}
+
+	return opt, nil
+}
+
+func WithNamespace(namespace string) CollectorOption {
+	return CollectorOption{
+		Namespace: namespace,
+	}
+}
+
+// WithSetLabel changes the set label. Use redblocks.SetName to keep cardinality low when keys have many suffixes.
+func WithSetLabel(fn func(key string) string) CollectorOption {
+	return CollectorOption{
+		SetLabel: fn,
+	}
+}
@@ -1,10 +0,0 @@
-// Code generated by github.com/izumin5210/gex. DO NOT EDIT.
-
-// +build tools
-
-package tools
-
-// tool dependencies
-import (
-	_ "github.com/izumin5210/cgt"
-)